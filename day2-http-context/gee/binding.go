@@ -0,0 +1,65 @@
+package gee
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Validator is implemented by bound values that check themselves
+type Validator interface {
+	Validate() error
+}
+
+// ShouldBindJSON decodes the JSON body into obj and validates it when obj
+// is a Validator. The error is an *Error of type ErrorTypeBind carrying the
+// response code: 415 for another media type, 413 over Engine.MaxBodyBytes,
+// 400 for malformed JSON and 422 for a failed validation.
+func (c *Context) ShouldBindJSON(obj interface{}) error {
+	mediaType, _, _ := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+	if mediaType != MIMEJSON && !strings.HasSuffix(mediaType, "+json") {
+		return bindError(http.StatusUnsupportedMediaType,
+			fmt.Errorf("content type %q is not JSON", mediaType))
+	}
+	body := c.Req.Body
+	if c.engine != nil && c.engine.MaxBodyBytes > 0 {
+		body = http.MaxBytesReader(c.Writer, body, c.engine.MaxBodyBytes)
+	}
+	if err := json.NewDecoder(body).Decode(obj); err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			return bindError(http.StatusRequestEntityTooLarge,
+				fmt.Errorf("request body exceeds %d bytes", tooLarge.Limit))
+		case errors.Is(err, io.EOF):
+			return bindError(http.StatusBadRequest, errors.New("request body is empty"))
+		default:
+			return bindError(http.StatusBadRequest, err)
+		}
+	}
+	if v, ok := obj.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return bindError(http.StatusUnprocessableEntity, err)
+		}
+	}
+	return nil
+}
+
+// BindJSON is ShouldBindJSON adding the error to c.Errors and aborting,
+// the ErrorHandler then answers with its code
+func (c *Context) BindJSON(obj interface{}) error {
+	err := c.ShouldBindJSON(obj)
+	if err != nil {
+		c.Error(err)
+		c.Abort()
+	}
+	return err
+}
+
+func bindError(code int, err error) *Error {
+	return &Error{Err: err, Type: ErrorTypeBind | ErrorTypePublic, Status: code}
+}
@@ -0,0 +1,149 @@
+package gee
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// Headers set by hosting platforms, see Engine.TrustedPlatform
+const (
+	PlatformCloudflare      = "CF-Connecting-IP"
+	PlatformGoogleAppEngine = "X-Appengine-Remote-Addr"
+	PlatformFlyIO           = "Fly-Client-IP"
+)
+
+// SetTrustedProxies sets the IPs and CIDR ranges, e.g. "10.0.0.0/8", of the
+// proxies allowed to report the client address in RemoteIPHeaders. No
+// proxy is trusted by default.
+func (engine *Engine) SetTrustedProxies(proxies []string) error {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		var (
+			prefix netip.Prefix
+			err    error
+		)
+		if strings.Contains(proxy, "/") {
+			prefix, err = netip.ParsePrefix(proxy)
+		} else {
+			var addr netip.Addr
+			addr, err = netip.ParseAddr(proxy)
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		if err != nil {
+			return err
+		}
+		if prefix.Addr().Is4In6() {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	engine.trustedProxies = prefixes
+	return nil
+}
+
+// isTrustedProxy reports whether addr belongs to a trusted proxy
+func (engine *Engine) isTrustedProxy(addr netip.Addr) bool {
+	for _, prefix := range engine.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoteIP returns the address of the peer of the connection
+func (c *Context) RemoteIP() string {
+	addr, ok := parseIP(c.Req.RemoteAddr)
+	if !ok {
+		return ""
+	}
+	return addr.String()
+}
+
+// ClientIP returns the address of the client. The TrustedPlatform header
+// wins when configured. Otherwise RemoteIPHeaders are read only if the peer
+// is a trusted proxy, walking the chain right to left and stopping at the
+// first untrusted hop, so clients can't spoof the address.
+func (c *Context) ClientIP() string {
+	engine := c.engine
+	if engine != nil && engine.TrustedPlatform != "" {
+		if addr, ok := parseIP(c.Req.Header.Get(engine.TrustedPlatform)); ok {
+			return addr.String()
+		}
+	}
+	peer, ok := parseIP(c.Req.RemoteAddr)
+	if !ok {
+		return ""
+	}
+	if engine == nil || !engine.isTrustedProxy(peer) {
+		return peer.String()
+	}
+	for _, header := range engine.RemoteIPHeaders {
+		values := c.Req.Header.Values(header)
+		if len(values) == 0 {
+			continue
+		}
+		var hops []string
+		if strings.EqualFold(header, "Forwarded") {
+			hops = forwardedFor(values)
+		} else {
+			hops = strings.Split(strings.Join(values, ","), ",")
+		}
+		if addr, ok := engine.firstUntrusted(hops); ok {
+			return addr.String()
+		}
+	}
+	return peer.String()
+}
+
+// firstUntrusted walks hops from the nearest proxy back to the client and
+// returns the first address that is not a trusted proxy
+func (engine *Engine) firstUntrusted(hops []string) (netip.Addr, bool) {
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseIP(hops[i])
+		if !ok {
+			return netip.Addr{}, false
+		}
+		if i == 0 || !engine.isTrustedProxy(addr) {
+			return addr, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// forwardedFor extracts the for= parameters of RFC 7239 Forwarded headers
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(strings.TrimSpace(key), "for") {
+					hops = append(hops, v)
+				}
+			}
+		}
+	}
+	return hops
+}
+
+// parseIP accepts "1.2.3.4", "1.2.3.4:80", "[::1]:80" and their quoted
+// forms, obfuscated or unknown nodes are rejected
+func parseIP(s string) (netip.Addr, bool) {
+	s = strings.Trim(strings.TrimSpace(s), `"`)
+	if s == "" {
+		return netip.Addr{}, false
+	}
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return addr.Unmap(), true
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
@@ -0,0 +1,93 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// clientIP returns what Context.ClientIP reports for a request from peer
+func clientIP(r *Engine, peer string, header http.Header) string {
+	var got string
+	r.GET("/ip", func(c *Context) { got = c.ClientIP() })
+	req := httptest.NewRequest("GET", "/ip", nil)
+	req.RemoteAddr = peer
+	req.Header = header
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	return got
+}
+
+func newProxiedEngine(t *testing.T) *Engine {
+	r := New()
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestClientIPUntrustedPeer(t *testing.T) {
+	header := http.Header{
+		"X-Forwarded-For": {"1.1.1.1"},
+		"X-Real-Ip":       {"1.1.1.1"},
+		"Forwarded":       {"for=1.1.1.1"},
+	}
+	if got := clientIP(newProxiedEngine(t), "203.0.113.9:4000", header); got != "203.0.113.9" {
+		t.Errorf("untrusted peer got %q, want its own address", got)
+	}
+	if got := clientIP(New(), "10.0.0.1:4000", header); got != "10.0.0.1" {
+		t.Errorf("no trusted proxies got %q, want the peer", got)
+	}
+}
+
+func TestClientIPForwardedFor(t *testing.T) {
+	tests := []struct {
+		name string
+		xff  []string
+		want string
+	}{
+		{"single hop", []string{"203.0.113.9"}, "203.0.113.9"},
+		// nginx appends the peer, anything before it came from the client
+		{"spoofed prefix", []string{"1.1.1.1, 203.0.113.9"}, "203.0.113.9"},
+		{"proxy chain", []string{"203.0.113.9, 10.0.0.7"}, "203.0.113.9"},
+		{"repeated headers", []string{"1.1.1.1", "203.0.113.9, 10.0.0.7"}, "203.0.113.9"},
+		{"all trusted", []string{"10.0.0.8, 10.0.0.7"}, "10.0.0.8"},
+		{"garbage", []string{"1.1.1.1, nonsense"}, "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		got := clientIP(newProxiedEngine(t), "10.0.0.1:4000", http.Header{"X-Forwarded-For": tt.xff})
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClientIPForwardedOptIn(t *testing.T) {
+	// a proxy appending to X-Forwarded-For passes the client's Forwarded through
+	header := http.Header{
+		"Forwarded":       {"for=1.1.1.1"},
+		"X-Forwarded-For": {"203.0.113.9"},
+	}
+	if got := clientIP(newProxiedEngine(t), "10.0.0.1:4000", header); got != "203.0.113.9" {
+		t.Errorf("Forwarded is read by default: got %q", got)
+	}
+
+	r := newProxiedEngine(t)
+	r.RemoteIPHeaders = []string{"Forwarded"}
+	header = http.Header{"Forwarded": {`for=1.1.1.1, for="[2001:db8::1]:443";proto=https`}}
+	if got := clientIP(r, "10.0.0.1:4000", header); got != "2001:db8::1" {
+		t.Errorf("opted in Forwarded got %q, want 2001:db8::1", got)
+	}
+	header = http.Header{"Forwarded": {"for=_hidden"}}
+	if got := clientIP(r, "10.0.0.1:4000", header); got != "10.0.0.1" {
+		t.Errorf("obfuscated node got %q, want the peer", got)
+	}
+}
+
+func TestClientIPTrustedPlatform(t *testing.T) {
+	r := New()
+	r.TrustedPlatform = PlatformCloudflare
+	header := http.Header{"Cf-Connecting-Ip": {"203.0.113.9"}, "X-Forwarded-For": {"1.1.1.1"}}
+	if got := clientIP(r, "198.51.100.1:4000", header); got != "203.0.113.9" {
+		t.Errorf("got %q, want the platform header", got)
+	}
+}
@@ -0,0 +1,294 @@
+// Package compress compresses responses with gzip or deflate, as
+// negotiated with the Accept-Encoding header.
+//
+//	r.Use(compress.Gzip())
+//
+// Small bodies, already encoded responses and content types that don't
+// compress well are sent as is. Flushes, e.g. from Context.Stream, flush
+// the compressor so streamed responses keep flowing.
+//
+// Decompress does the reverse for request bodies sent with a
+// Content-Encoding.
+package compress
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gee"
+)
+
+// Config configures New
+type Config struct {
+	// Level is a compress/flate level, flate.DefaultCompression by default
+	Level int
+	// MinLength is the body size from which responses are compressed,
+	// 1024 by default
+	MinLength int
+	// ExcludedContentTypes are media types, or prefixes ending with "/"
+	// such as "image/", that are never compressed
+	ExcludedContentTypes []string
+	// ExcludedPaths are path prefixes that are never compressed
+	ExcludedPaths []string
+}
+
+// DefaultExcludedContentTypes are already compressed formats
+var DefaultExcludedContentTypes = []string{
+	"image/", "video/", "audio/",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/zstd", "application/x-7z-compressed", "application/x-rar-compressed",
+	"font/woff", "font/woff2",
+}
+
+// Gzip compresses responses with the default config
+func Gzip() gee.HandlerFunc {
+	return New(Config{})
+}
+
+type compressor struct {
+	minLength int
+	excluded  []string
+	paths     []string
+	gzipPool  sync.Pool
+	zlibPool  sync.Pool
+}
+
+// New returns the compression middleware, it panics on an invalid level
+func New(config Config) gee.HandlerFunc {
+	if config.Level == 0 {
+		config.Level = flate.DefaultCompression
+	}
+	if config.Level < flate.HuffmanOnly || config.Level > flate.BestCompression {
+		panic("compress: invalid level " + strconv.Itoa(config.Level))
+	}
+	if config.MinLength == 0 {
+		config.MinLength = 1024
+	}
+	if config.ExcludedContentTypes == nil {
+		config.ExcludedContentTypes = DefaultExcludedContentTypes
+	}
+	level := config.Level
+	c := &compressor{
+		minLength: config.MinLength,
+		excluded:  config.ExcludedContentTypes,
+		paths:     config.ExcludedPaths,
+	}
+	c.gzipPool.New = func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, level)
+		return w
+	}
+	// the "deflate" coding is the zlib format of RFC 1950, not raw deflate
+	c.zlibPool.New = func() interface{} {
+		w, _ := zlib.NewWriterLevel(io.Discard, level)
+		return w
+	}
+	return c.handle
+}
+
+func (c *compressor) handle(ctx *gee.Context) {
+	for _, prefix := range c.paths {
+		if strings.HasPrefix(ctx.Req.URL.Path, prefix) {
+			ctx.Next()
+			return
+		}
+	}
+	ctx.Writer.Header().Add("Vary", "Accept-Encoding")
+	encoding := ctx.NegotiateEncoding("gzip", "deflate")
+	if encoding == "" || ctx.Req.Method == http.MethodHead || ctx.Req.Header.Get("Upgrade") != "" {
+		ctx.Next()
+		return
+	}
+	w := &responseWriter{ResponseWriter: ctx.Writer, c: c, encoding: encoding}
+	ctx.Writer = w
+	done := false
+	defer func() {
+		ctx.Writer = w.ResponseWriter
+		if done {
+			w.close()
+		} else {
+			// a handler panicked, leave the response to Recovery
+			w.release()
+		}
+	}()
+	ctx.Next()
+	done = true
+}
+
+// responseWriter holds the body back until MinLength bytes show whether
+// it is worth compressing
+type responseWriter struct {
+	http.ResponseWriter
+	c        *compressor
+	encoding string
+	status   int
+	buf      []byte
+	decided  bool
+	// cw is the compressor, nil when the response is sent as is
+	cw interface {
+		io.Writer
+		Flush() error
+		Close() error
+		Reset(io.Writer)
+	}
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if code < 200 || w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+	if cl := w.Header().Get("Content-Length"); cl != "" {
+		if n, err := strconv.Atoi(cl); err == nil && n < w.c.minLength {
+			w.decide(false)
+		}
+	}
+	if code == http.StatusNoContent || code == http.StatusNotModified || code == http.StatusPartialContent {
+		w.decide(false)
+	}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.c.minLength {
+			return len(b), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.cw != nil {
+		return w.cw.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends what is buffered, compressed when eligible regardless of
+// MinLength since a streamed body has no known size
+func (w *responseWriter) Flush() {
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.decide(true)
+	}
+	if w.cw != nil {
+		w.cw.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Written reports whether the handlers wrote a response, sent or held back
+func (w *responseWriter) Written() bool {
+	return w.status != 0
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide sends the header, with compression if wanted and eligible, then
+// the buffered body
+func (w *responseWriter) decide(compress bool) error {
+	w.decided = true
+	header := w.Header()
+	if compress && w.eligible() {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		// the compressed body is another representation
+		if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			header.Set("ETag", "W/"+etag)
+		}
+		if w.encoding == "gzip" {
+			gz := w.c.gzipPool.Get().(*gzip.Writer)
+			gz.Reset(w.ResponseWriter)
+			w.cw = gz
+		} else {
+			zw := w.c.zlibPool.Get().(*zlib.Writer)
+			zw.Reset(w.ResponseWriter)
+			w.cw = zw
+		}
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.cw != nil {
+		_, err = w.cw.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+// eligible reports whether the response may be compressed
+func (w *responseWriter) eligible() bool {
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		// set it now, net/http would sniff the compressed bytes instead
+		contentType = http.DetectContentType(w.buf)
+		header.Set("Content-Type", contentType)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, excluded := range w.c.excluded {
+		if mediaType == excluded || strings.HasSuffix(excluded, "/") && strings.HasPrefix(mediaType, excluded) {
+			return false
+		}
+	}
+	return true
+}
+
+// close sends a body shorter than MinLength as is, or ends the compressed
+// stream and returns the compressor to its pool
+func (w *responseWriter) close() {
+	if !w.decided {
+		if w.status == 0 && len(w.buf) == 0 {
+			return
+		}
+		w.decide(false)
+		return
+	}
+	if w.cw != nil {
+		w.cw.Close()
+	}
+	w.release()
+}
+
+// release drops the buffered body and returns the compressor to its pool
+func (w *responseWriter) release() {
+	w.buf = nil
+	switch cw := w.cw.(type) {
+	case *gzip.Writer:
+		cw.Reset(io.Discard)
+		w.c.gzipPool.Put(cw)
+	case *zlib.Writer:
+		cw.Reset(io.Discard)
+		w.c.zlibPool.Put(cw)
+	}
+	w.cw = nil
+}
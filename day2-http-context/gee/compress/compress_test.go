@@ -0,0 +1,81 @@
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gee"
+)
+
+func init() {
+	gee.SetMode(gee.TestMode)
+}
+
+var body = strings.Repeat("gee compress ", 200)
+
+func TestEncodings(t *testing.T) {
+	r := gee.New()
+	r.Use(Gzip())
+	r.GET("/", func(c *gee.Context) {
+		c.String(http.StatusOK, body)
+	})
+	readers := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		// deflate is the zlib format, a raw deflate stream fails the header check
+		"deflate": func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+	}
+	for encoding, newReader := range readers {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", encoding)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Header().Get("Content-Encoding"); got != encoding {
+			t.Fatalf("Content-Encoding = %q, want %q", got, encoding)
+		}
+		zr, err := newReader(w.Body)
+		if err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		if got, err := io.ReadAll(zr); err != nil || string(got) != body {
+			t.Errorf("%s: decoded %d bytes, err %v", encoding, len(got), err)
+		}
+	}
+}
+
+func TestSmallBodyNotCompressed(t *testing.T) {
+	r := gee.New()
+	r.Use(Gzip())
+	r.GET("/", func(c *gee.Context) {
+		c.String(http.StatusOK, "tiny")
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != "tiny" {
+		t.Errorf("got %q encoded as %q", w.Body.String(), w.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestErrorAfterHeldBackWrite(t *testing.T) {
+	r := gee.New()
+	r.Use(Gzip())
+	r.GET("/", func(c *gee.Context) {
+		// below MinLength, the body is still held back by the compressor
+		c.String(http.StatusOK, "tiny")
+		c.Error(errors.New("late failure"))
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "tiny" {
+		t.Errorf("got %d %q, want the handler response alone", w.Code, w.Body.String())
+	}
+}
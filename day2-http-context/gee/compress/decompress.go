@@ -0,0 +1,92 @@
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"gee"
+)
+
+// DecompressConfig configures Decompress
+type DecompressConfig struct {
+	// MaxSize limits the decompressed body, 10 MiB by default. Reading
+	// past it fails with *http.MaxBytesError, which binding reports as 413.
+	MaxSize int64
+}
+
+// Decompress decodes request bodies sent with Content-Encoding gzip or
+// deflate so handlers read them as is. Other codings are answered with
+// 415 and an Accept-Encoding header listing the supported ones.
+func Decompress(config DecompressConfig) gee.HandlerFunc {
+	if config.MaxSize <= 0 {
+		config.MaxSize = 10 << 20
+	}
+	return func(c *gee.Context) {
+		req := c.Req
+		codings := parseCodings(req.Header.Get("Content-Encoding"))
+		if len(codings) == 0 || req.Body == nil || req.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		body := req.Body
+		// codings are listed in the order they were applied
+		for i := len(codings) - 1; i >= 0; i-- {
+			var err error
+			switch codings[i] {
+			case "identity":
+			case "gzip", "x-gzip":
+				body, err = gzip.NewReader(body)
+			case "deflate":
+				// the zlib format of RFC 1950, not raw deflate
+				body, err = zlib.NewReader(body)
+			default:
+				c.SetHeader("Accept-Encoding", "gzip, deflate")
+				c.Error(fmt.Errorf("content encoding %q is not supported", codings[i])).
+					SetType(gee.ErrorTypePublic).SetStatus(http.StatusUnsupportedMediaType)
+				c.Abort()
+				return
+			}
+			if err != nil {
+				c.Error(errors.New("malformed " + codings[i] + " body")).
+					SetType(gee.ErrorTypePublic).SetStatus(http.StatusBadRequest)
+				c.Abort()
+				return
+			}
+		}
+		req.Body = &decompressedBody{
+			ReadCloser: http.MaxBytesReader(c.Writer, body, config.MaxSize),
+			raw:        req.Body,
+		}
+		req.Header.Del("Content-Encoding")
+		req.Header.Del("Content-Length")
+		req.ContentLength = -1
+		c.Next()
+	}
+}
+
+// parseCodings lowercases the comma separated codings of a header
+func parseCodings(header string) []string {
+	var codings []string
+	for _, coding := range strings.Split(header, ",") {
+		if coding = strings.ToLower(strings.TrimSpace(coding)); coding != "" {
+			codings = append(codings, coding)
+		}
+	}
+	return codings
+}
+
+// decompressedBody closes the decoders and the original body
+type decompressedBody struct {
+	io.ReadCloser
+	raw io.ReadCloser
+}
+
+func (b *decompressedBody) Close() error {
+	b.ReadCloser.Close()
+	return b.raw.Close()
+}
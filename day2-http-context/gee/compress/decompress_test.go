@@ -0,0 +1,67 @@
+package compress
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gee"
+)
+
+func zlibBytes(s string) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(s))
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	r := gee.New()
+	r.Use(Decompress(DecompressConfig{}))
+	r.POST("/", func(c *gee.Context) {
+		data, err := io.ReadAll(c.Req.Body)
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, string(data))
+	})
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write(zlibBytes("hello"))
+	gw.Close()
+	var raw bytes.Buffer
+	fw, _ := flate.NewWriter(&raw, flate.DefaultCompression)
+	fw.Write([]byte("hello"))
+	fw.Close()
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		code     int
+	}{
+		{"deflate", "deflate", zlibBytes("hello"), http.StatusOK},
+		{"deflate then gzip", "deflate, gzip", gz.Bytes(), http.StatusOK},
+		{"raw deflate", "deflate", raw.Bytes(), http.StatusBadRequest},
+		{"unsupported", "br", []byte("x"), http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(tt.body))
+		req.Header.Set("Content-Encoding", tt.encoding)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s: got %d %q, want %d", tt.name, w.Code, w.Body.String(), tt.code)
+		} else if tt.code == http.StatusOK && w.Body.String() != "hello" {
+			t.Errorf("%s: got body %q", tt.name, w.Body.String())
+		}
+	}
+}
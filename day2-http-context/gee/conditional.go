@@ -0,0 +1,227 @@
+package gee
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETag sets the ETag header and evaluates the request preconditions against
+// it. It returns true when the request was answered with 304 or 412, the
+// handler should then return without writing a body. When combined with
+// LastModified, call ETag first.
+func (c *Context) ETag(tag string, weak bool) bool {
+	c.SetHeader("ETag", formatETag(tag, weak))
+	return c.checkPreconditions()
+}
+
+// LastModified sets the Last-Modified header and evaluates the request
+// preconditions against it, see ETag
+func (c *Context) LastModified(t time.Time) bool {
+	if !t.IsZero() && !t.Equal(time.Unix(0, 0)) {
+		c.SetHeader("Last-Modified", t.UTC().Format(http.TimeFormat))
+	}
+	return c.checkPreconditions()
+}
+
+// checkPreconditions answers the request with 304 or 412 when the
+// validators in the response headers fail its conditions
+func (c *Context) checkPreconditions() bool {
+	header := c.Writer.Header()
+	var modtime time.Time
+	if lm := header.Get("Last-Modified"); lm != "" {
+		modtime, _ = http.ParseTime(lm)
+	}
+	code := evalPreconditions(c.Req, header.Get("ETag"), modtime)
+	if code == 0 {
+		return false
+	}
+	if code == http.StatusNotModified {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		header.Del("Content-Encoding")
+	}
+	c.Status(code)
+	c.Abort()
+	return true
+}
+
+// evalPreconditions follows the order of RFC 9110 section 13.2.2 and
+// returns 304, 412 or 0 when the request should proceed
+func evalPreconditions(r *http.Request, etag string, modtime time.Time) int {
+	if im := r.Header.Get("If-Match"); im != "" {
+		if !matchETag(im, etag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius := r.Header.Get("If-Unmodified-Since"); ius != "" && !modtime.IsZero() {
+		if t, err := http.ParseTime(ius); err == nil && modtime.Truncate(time.Second).After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if matchETag(inm, etag, true) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && safe && !modtime.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !modtime.Truncate(time.Second).After(t) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// formatETag quotes tag unless it already is an entity tag
+func formatETag(tag string, weak bool) string {
+	if _, _, ok := scanETag(tag); !ok {
+		tag = `"` + strings.ReplaceAll(tag, `"`, "") + `"`
+	} else {
+		tag = strings.TrimPrefix(tag, "W/")
+	}
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// matchETag reports whether the If-Match or If-None-Match list matches
+// etag, using the weak comparison when weak is set
+func matchETag(list, etag string, weak bool) bool {
+	list = strings.TrimSpace(list)
+	if list == "*" {
+		return true
+	}
+	if etag == "" {
+		return false
+	}
+	for list != "" {
+		list = strings.TrimLeft(list, " \t,")
+		if list == "" {
+			break
+		}
+		tag, rest, ok := scanETag(list)
+		if !ok {
+			return false
+		}
+		if weak && weakMatch(tag, etag) || !weak && strongMatch(tag, etag) {
+			return true
+		}
+		list = rest
+	}
+	return false
+}
+
+// scanETag reads the entity tag at the start of s and returns the rest
+func scanETag(s string) (etag string, rest string, ok bool) {
+	start := 0
+	if strings.HasPrefix(s, "W/") {
+		start = 2
+	}
+	if len(s)-start < 2 || s[start] != '"' {
+		return "", "", false
+	}
+	for i := start + 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			return s[:i+1], s[i+1:], true
+		case c == 0x21 || c >= 0x23 && c <= 0x7E || c >= 0x80:
+		default:
+			return "", "", false
+		}
+	}
+	return "", "", false
+}
+
+func strongMatch(a, b string) bool {
+	return a == b && !strings.HasPrefix(a, "W/")
+}
+
+func weakMatch(a, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+// AutoETag buffers successful GET and HEAD responses and tags them with a
+// strong ETag hashed from the body, answering 304 when the client has it.
+// Handlers that set their own ETag or stream are left alone.
+func AutoETag() HandlerFunc {
+	return func(c *Context) {
+		if c.Method != http.MethodGet && c.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+		w := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		if w.flushed {
+			return
+		}
+		status := w.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status == http.StatusOK && w.Header().Get("ETag") == "" {
+			sum := sha256.Sum256(w.buf.Bytes())
+			if c.ETag(hex.EncodeToString(sum[:16]), false) {
+				return
+			}
+		}
+		if w.status != 0 || w.buf.Len() > 0 {
+			c.Writer.WriteHeader(status)
+		}
+		c.Writer.Write(w.buf.Bytes())
+	}
+}
+
+// bufferedWriter holds the response back until the handlers are done. A
+// Flush hands the response over to the client and disables buffering.
+type bufferedWriter struct {
+	http.ResponseWriter
+	buf     bytes.Buffer
+	status  int
+	flushed bool
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if w.flushed {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if w.flushed {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+func (w *bufferedWriter) Flush() {
+	if !w.flushed {
+		w.flushed = true
+		if w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Written reports whether the handlers wrote a response, sent or held back
+func (w *bufferedWriter) Written() bool {
+	return w.status != 0 || w.buf.Len() > 0 || w.flushed
+}
+
+func (w *bufferedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,239 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEvalPreconditions(t *testing.T) {
+	modtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	before := modtime.Add(-time.Hour).Format(http.TimeFormat)
+	at := modtime.Format(http.TimeFormat)
+	etag := `"v2"`
+	tests := []struct {
+		name   string
+		method string
+		header map[string]string
+		want   int
+	}{
+		{"no conditions", "GET", nil, 0},
+
+		// step 1, If-Match uses the strong comparison
+		{"If-Match hit", "PUT", map[string]string{"If-Match": `"v1", "v2"`}, 0},
+		{"If-Match miss", "PUT", map[string]string{"If-Match": `"v1"`}, 412},
+		{"If-Match weak tag", "PUT", map[string]string{"If-Match": `W/"v2"`}, 412},
+		{"If-Match *", "PUT", map[string]string{"If-Match": "*"}, 0},
+		{"If-Match malformed", "PUT", map[string]string{"If-Match": `v2`}, 412},
+
+		// step 2, If-Unmodified-Since only without If-Match
+		{"If-Unmodified-Since modified", "PUT", map[string]string{"If-Unmodified-Since": before}, 412},
+		{"If-Unmodified-Since unmodified", "PUT", map[string]string{"If-Unmodified-Since": at}, 0},
+		{"If-Unmodified-Since invalid date", "PUT", map[string]string{"If-Unmodified-Since": "yesterday"}, 0},
+		{"If-Match wins over If-Unmodified-Since", "PUT", map[string]string{"If-Match": `"v2"`, "If-Unmodified-Since": before}, 0},
+
+		// step 3, If-None-Match uses the weak comparison, 304 only for GET and HEAD
+		{"If-None-Match GET", "GET", map[string]string{"If-None-Match": `"v2"`}, 304},
+		{"If-None-Match HEAD", "HEAD", map[string]string{"If-None-Match": `"v2"`}, 304},
+		{"If-None-Match POST", "POST", map[string]string{"If-None-Match": `"v2"`}, 412},
+		{"If-None-Match weak tag", "GET", map[string]string{"If-None-Match": `W/"v2"`}, 304},
+		{"If-None-Match miss", "GET", map[string]string{"If-None-Match": `"v1"`}, 0},
+		{"If-None-Match * GET", "GET", map[string]string{"If-None-Match": "*"}, 304},
+		{"If-None-Match * PUT", "PUT", map[string]string{"If-None-Match": "*"}, 412},
+		{"If-None-Match list", "GET", map[string]string{"If-None-Match": `"v1",W/"v2"`}, 304},
+		{"If-None-Match malformed", "GET", map[string]string{"If-None-Match": `v2`}, 0},
+		{"If-Match fails before If-None-Match", "GET", map[string]string{"If-Match": `"v1"`, "If-None-Match": `"v2"`}, 412},
+
+		// step 4, If-Modified-Since only without If-None-Match and for GET and HEAD
+		{"If-Modified-Since unmodified", "GET", map[string]string{"If-Modified-Since": at}, 304},
+		{"If-Modified-Since modified", "GET", map[string]string{"If-Modified-Since": before}, 0},
+		{"If-Modified-Since POST", "POST", map[string]string{"If-Modified-Since": at}, 0},
+		{"If-Modified-Since invalid date", "GET", map[string]string{"If-Modified-Since": "yesterday"}, 0},
+		{"If-None-Match wins over If-Modified-Since", "GET", map[string]string{"If-None-Match": `"v1"`, "If-Modified-Since": at}, 0},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/", nil)
+		for key, value := range tt.header {
+			req.Header.Set(key, value)
+		}
+		if got := evalPreconditions(req, etag, modtime.Add(500*time.Millisecond)); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEvalPreconditionsWithoutValidators(t *testing.T) {
+	tests := []struct {
+		header map[string]string
+		want   int
+	}{
+		{map[string]string{"If-Match": `"v1"`}, 412},
+		{map[string]string{"If-Match": "*"}, 0},
+		{map[string]string{"If-None-Match": `"v1"`}, 0},
+		{map[string]string{"If-Modified-Since": time.Now().Format(http.TimeFormat)}, 0},
+		{map[string]string{"If-Unmodified-Since": time.Now().Format(http.TimeFormat)}, 0},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		for key, value := range tt.header {
+			req.Header.Set(key, value)
+		}
+		if got := evalPreconditions(req, "", time.Time{}); got != tt.want {
+			t.Errorf("%v: got %d, want %d", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		list, etag string
+		weak       bool
+		want       bool
+	}{
+		{`"a"`, `"a"`, false, true},
+		{`"a"`, `W/"a"`, false, false},
+		{`W/"a"`, `W/"a"`, false, false},
+		{`W/"a"`, `"a"`, true, true},
+		{`"a"`, `W/"a"`, true, true},
+		{` "b" , "a" `, `"a"`, false, true},
+		{`,,"a"`, `"a"`, false, true},
+		{`"b"`, `"a"`, true, false},
+		{`*`, `"a"`, false, true},
+		{` * `, "", true, true},
+		{`"a"`, "", true, false},
+		{`a`, `"a"`, true, false},
+		{`"a`, `"a"`, true, false},
+		{`"a b"`, `"a b"`, true, false},
+		{`bogus, "a"`, `"a"`, true, false},
+		{`"a", bogus`, `"a"`, true, true},
+	}
+	for _, tt := range tests {
+		if got := matchETag(tt.list, tt.etag, tt.weak); got != tt.want {
+			t.Errorf("matchETag(%q, %q, %v) = %v, want %v", tt.list, tt.etag, tt.weak, got, tt.want)
+		}
+	}
+}
+
+func TestScanETag(t *testing.T) {
+	tests := []struct {
+		s, etag, rest string
+		ok            bool
+	}{
+		{`"a", "b"`, `"a"`, `, "b"`, true},
+		{`W/"a"`, `W/"a"`, "", true},
+		{`""`, `""`, "", true},
+		{`"é"`, `"é"`, "", true},
+		{`"`, "", "", false},
+		{`W/`, "", "", false},
+		{`w/"a"`, "", "", false},
+		{`"a`, "", "", false},
+		{`"a"b"`, `"a"`, `b"`, true},
+		{"\"a\tb\"", "", "", false},
+	}
+	for _, tt := range tests {
+		etag, rest, ok := scanETag(tt.s)
+		if etag != tt.etag || rest != tt.rest || ok != tt.ok {
+			t.Errorf("scanETag(%q) = %q, %q, %v", tt.s, etag, rest, ok)
+		}
+	}
+}
+
+func TestFormatETag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		weak bool
+		want string
+	}{
+		{"v1", false, `"v1"`},
+		{"v1", true, `W/"v1"`},
+		{`"v1"`, false, `"v1"`},
+		{`W/"v1"`, false, `"v1"`},
+		{`"v1"`, true, `W/"v1"`},
+		{`v"1`, false, `"v1"`},
+	}
+	for _, tt := range tests {
+		if got := formatETag(tt.tag, tt.weak); got != tt.want {
+			t.Errorf("formatETag(%q, %v) = %q, want %q", tt.tag, tt.weak, got, tt.want)
+		}
+	}
+}
+
+func TestETagHandler(t *testing.T) {
+	modtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := New()
+	r.GET("/doc", func(c *Context) {
+		c.SetHeader("Content-Type", "text/plain")
+		if c.ETag("v2", false) || c.LastModified(modtime) {
+			return
+		}
+		c.String(http.StatusOK, "body")
+	})
+	r.POST("/doc", func(c *Context) {
+		if c.ETag("v2", false) {
+			return
+		}
+		c.String(http.StatusOK, "saved")
+	})
+	tests := []struct {
+		method, header, value string
+		code                  int
+		body                  string
+	}{
+		{"GET", "", "", 200, "body"},
+		{"GET", "If-None-Match", `"v2"`, 304, ""},
+		{"GET", "If-Modified-Since", modtime.Format(http.TimeFormat), 304, ""},
+		{"POST", "If-Match", `"v1"`, 412, ""},
+		{"POST", "If-Match", `"v2"`, 200, "saved"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/doc", nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("%s %s: got %d %q, want %d %q", tt.method, tt.header, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+		if w.Header().Get("ETag") != `"v2"` {
+			t.Errorf("%s %s: ETag = %q", tt.method, tt.header, w.Header().Get("ETag"))
+		}
+		if tt.code == 304 && w.Header().Get("Content-Type") != "" {
+			t.Errorf("304 kept Content-Type %q", w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestAutoETag(t *testing.T) {
+	r := New()
+	r.Use(AutoETag())
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "hello")
+	})
+	r.GET("/own", func(c *Context) {
+		c.SetHeader("ETag", `"mine"`)
+		c.String(http.StatusOK, "hello")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "hello" || etag == "" {
+		t.Fatalf("got %d %q with ETag %q", w.Code, w.Body.String(), etag)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("revalidation got %d %q, want an empty 304", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/own", nil))
+	if w.Header().Get("ETag") != `"mine"` || w.Body.String() != "hello" {
+		t.Errorf("own ETag replaced: %q %q", w.Header().Get("ETag"), w.Body.String())
+	}
+}
@@ -1,13 +1,32 @@
 package gee
 
 import (
-	"encoding/json"
-	"fmt"
+	"bytes"
+	"encoding/xml"
+	"log/slog"
+	"math"
 	"net/http"
+
+	"gee/render"
 )
 
 type H map[string]interface{}
 
+// MarshalXML allows H to be rendered with Context.XML
+func (h H) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "map"}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for key, value := range h {
+		elem := xml.StartElement{Name: xml.Name{Local: key}}
+		if err := e.EncodeElement(value, elem); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(xml.EndElement{Name: start.Name})
+}
+
 type Context struct {
 	// origin objects
 	Writer http.ResponseWriter
@@ -15,17 +34,90 @@ type Context struct {
 	// request info
 	Path   string
 	Method string
+	Params map[string]string
 	// response info
 	StatusCode int
+	// middleware
+	handlers []HandlerFunc
+	index    int
+	// Keys holds values shared by the handlers of one request
+	Keys map[string]interface{}
+	// Errors are the errors added by Context.Error
+	Errors        Errors
+	handledErrors int
+	loggedErrors  int
+	// engine pointer
+	engine *Engine
+	// fullPath is the pattern of the matched route
+	fullPath  string
+	requestID string
+	logger    *slog.Logger
 }
 
+// abortIndex is past any chain so that Next stops calling handlers
+const abortIndex = math.MaxInt / 2
+
 func newContext(w http.ResponseWriter, req *http.Request) *Context {
 	return &Context{
 		Writer: w,
 		Req:    req,
 		Path:   req.URL.Path,
 		Method: req.Method,
+		index:  -1,
+	}
+}
+
+// Next runs the remaining handlers of the chain, a middleware calls it to
+// do work after the handlers that follow it
+func (c *Context) Next() {
+	c.index++
+	s := len(c.handlers)
+	for ; c.index < s; c.index++ {
+		c.handlers[c.index](c)
+	}
+	// the handlers are done, render their errors before the middlewares
+	// resume so that these see the final response
+	if c.engine != nil {
+		c.engine.handleErrors(c)
+	}
+}
+
+// Abort prevents the pending handlers of the chain from running
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+// IsAborted reports whether Abort was called
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+// Set stores value under key for the rest of the request
+func (c *Context) Set(key string, value interface{}) {
+	if c.Keys == nil {
+		c.Keys = make(map[string]interface{})
 	}
+	c.Keys[key] = value
+}
+
+// Get returns the value stored under key
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	value, exists = c.Keys[key]
+	return
+}
+
+// MustGet returns the value stored under key and panics if there is none
+func (c *Context) MustGet(key string) interface{} {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic("gee: key \"" + key + "\" does not exist")
+}
+
+// Param returns the value of the dynamic route segment key, e.g. :lang or *filepath
+func (c *Context) Param(key string) string {
+	value, _ := c.Params[key]
+	return value
 }
 
 func (c *Context) PostForm(key string) string {
@@ -41,32 +133,154 @@ func (c *Context) Status(code int) {
 	c.Writer.WriteHeader(code)
 }
 
+// Written reports whether the handlers wrote the status or a part of the
+// body, including a response still held back by a buffering middleware
+func (c *Context) Written() bool {
+	w := c.Writer
+	for w != nil {
+		if ww, ok := w.(interface{ Written() bool }); ok && ww.Written() {
+			return true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	return false
+}
+
 func (c *Context) SetHeader(key string, value string) {
 	c.Writer.Header().Set(key, value)
 }
 
-func (c *Context) String(code int, format string, values ...interface{}) {
-	c.SetHeader("Content-Type", "text/plain")
+// Render writes the header, the status code and then the body produced by r.
+// The body is produced before anything is sent, so when r fails the error
+// goes to c.Error and the ErrorHandler answers instead. A negative code
+// leaves the status alone, for appending to a response that is already
+// under way: a failure is then recorded with c.Error and aborts the chain.
+func (c *Context) Render(code int, r render.Render) {
+	if code < 0 {
+		if err := r.Render(c.Writer); err != nil {
+			c.Error(err)
+			c.Abort()
+		}
+		return
+	}
+	if !bodyAllowedForStatus(code) {
+		r.WriteContentType(c.Writer)
+		c.Status(code)
+		return
+	}
+	buf := &renderBuffer{header: c.Writer.Header().Clone()}
+	if err := r.Render(buf); err != nil {
+		c.Error(err)
+		return
+	}
+	header := c.Writer.Header()
+	for key, values := range buf.header {
+		header[key] = values
+	}
 	c.Status(code)
-	c.Writer.Write([]byte(fmt.Sprintf(format, values...)))
+	c.Writer.Write(buf.body.Bytes())
+}
+
+// renderBuffer collects what a render.Render writes so that nothing
+// reaches the client when it fails
+type renderBuffer struct {
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (b *renderBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *renderBuffer) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
+
+func (b *renderBuffer) WriteHeader(int) {}
+
+func (c *Context) String(code int, format string, values ...interface{}) {
+	c.Render(code, render.String{Format: format, Data: values})
 }
 
 func (c *Context) JSON(code int, obj interface{}) {
-	c.SetHeader("Content-Type", "application/json")
-	c.Status(code)
-	encoder := json.NewEncoder(c.Writer)
-	if err := encoder.Encode(obj); err != nil {
-		http.Error(c.Writer, err.Error(), 500)
+	c.Render(code, render.JSON{Data: obj})
+}
+
+// IndentedJSON renders obj as pretty-printed JSON, handy while debugging
+func (c *Context) IndentedJSON(code int, obj interface{}) {
+	c.Render(code, render.IndentedJSON{Data: obj})
+}
+
+// SecureJSON renders obj as JSON prefixed with "while(1);"
+func (c *Context) SecureJSON(code int, obj interface{}) {
+	c.Render(code, render.SecureJSON{Prefix: render.DefaultSecureJSONPrefix, Data: obj})
+}
+
+// JSONP renders obj wrapped in the function named by the "callback" query,
+// it falls back to plain JSON when no callback is given
+func (c *Context) JSONP(code int, obj interface{}) {
+	callback := c.Query("callback")
+	if callback != "" && !render.ValidCallback(callback) {
+		c.String(http.StatusBadRequest, "invalid callback: %q\n", callback)
+		return
 	}
+	c.Render(code, render.JsonpJSON{Callback: callback, Data: obj})
+}
+
+// AsciiJSON renders obj as JSON with non-ASCII characters escaped
+func (c *Context) AsciiJSON(code int, obj interface{}) {
+	c.Render(code, render.AsciiJSON{Data: obj})
+}
+
+// PureJSON renders obj as JSON without replacing <, > and & with unicode escapes
+func (c *Context) PureJSON(code int, obj interface{}) {
+	c.Render(code, render.PureJSON{Data: obj})
+}
+
+func (c *Context) XML(code int, obj interface{}) {
+	c.Render(code, render.XML{Data: obj})
+}
+
+func (c *Context) YAML(code int, obj interface{}) {
+	c.Render(code, render.YAML{Data: obj})
+}
+
+// ProtoBuf renders obj, encoded bytes or a message with Marshal, as protobuf
+func (c *Context) ProtoBuf(code int, obj interface{}) {
+	c.Render(code, render.ProtoBuf{Data: obj})
 }
 
 func (c *Context) Data(code int, data []byte) {
-	c.Status(code)
-	c.Writer.Write(data)
+	c.Render(code, render.Data{Data: data})
 }
 
-func (c *Context) HTML(code int, html string) {
-	c.SetHeader("Content-Type", "text/html")
-	c.Status(code)
-	c.Writer.Write([]byte(html))
+// HTML renders the template name, loaded with one of the Engine.LoadHTML* methods
+func (c *Context) HTML(code int, name string, data interface{}) {
+	if c.engine == nil || c.engine.HTMLRender == nil {
+		c.String(http.StatusInternalServerError, "html templates are not loaded\n")
+		return
+	}
+	instance := c.engine.HTMLRender.Instance(name, data)
+	if r, ok := instance.(render.HTMLError); ok {
+		c.String(http.StatusInternalServerError, "%v\n", r.Err)
+		return
+	}
+	c.Render(code, instance)
+}
+
+// bodyAllowedForStatus reports whether a response with status may carry a body
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent:
+		return false
+	case status == http.StatusNotModified:
+		return false
+	}
+	return true
 }
@@ -0,0 +1,127 @@
+package gee
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// failingRender writes part of a body, then fails
+type failingRender struct{}
+
+func (failingRender) Render(w http.ResponseWriter) error {
+	w.Write([]byte("partial"))
+	return errors.New("encoder broke")
+}
+
+func (failingRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/x-broken")
+}
+
+func TestRenderFailure(t *testing.T) {
+	handlers := map[string]HandlerFunc{
+		"json":   func(c *Context) { c.JSON(http.StatusOK, make(chan int)) },
+		"yaml":   func(c *Context) { c.YAML(http.StatusOK, H{"f": func() {}}) },
+		"custom": func(c *Context) { c.Render(http.StatusOK, failingRender{}) },
+	}
+	for name, handler := range handlers {
+		r := New()
+		r.GET("/", handler)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("%s: got %d, want 500", name, w.Code)
+		}
+		body := w.Body.String()
+		if strings.Contains(body, "partial") || strings.Contains(body, "unsupported") || strings.Contains(body, "encoder") {
+			t.Errorf("%s: body %q leaks the failed render", name, body)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+			t.Errorf("%s: Content-Type = %q, want the one of the error response", name, ct)
+		}
+	}
+}
+
+func TestRenderFailureWhileStreaming(t *testing.T) {
+	r := New()
+	var errs Errors
+	r.Use(func(c *Context) {
+		c.Next()
+		errs = c.Errors
+	})
+	after := false
+	r.Use(func(c *Context) {
+		c.String(http.StatusOK, "start\n")
+		c.Render(-1, failingRender{})
+		c.Next()
+	})
+	r.GET("/", func(c *Context) {
+		after = true
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "start\npartial" {
+		t.Errorf("got %d %q, want the stream without error text", w.Code, w.Body.String())
+	}
+	if len(errs) != 1 || errs[0].Error() != "encoder broke" {
+		t.Errorf("Errors = %v", errs)
+	}
+	if after {
+		t.Errorf("the chain went on after the failed render")
+	}
+}
+
+func TestRenderSuccess(t *testing.T) {
+	r := New()
+	r.GET("/", func(c *Context) {
+		c.SetHeader("Content-Type", "application/vnd.api+json")
+		c.JSON(http.StatusCreated, H{"ok": true})
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != `{"ok":true}` {
+		t.Errorf("got %d %q", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/vnd.api+json" {
+		t.Errorf("Content-Type = %q, want the one set by the handler", ct)
+	}
+}
+
+func TestHTMLReloadInDebugMode(t *testing.T) {
+	defer SetMode(Mode())
+	defer func(w io.Writer) { DefaultWriter = w }(DefaultWriter)
+	DefaultWriter = io.Discard
+
+	for mode, want := range map[string]string{DebugMode: "<b>v2</b>", ReleaseMode: "<b>v1</b>"} {
+		SetMode(mode)
+		fsys := fstest.MapFS{
+			"base.tmpl": {Data: []byte(`{{define "base"}}<b>{{block "body" .}}{{end}}</b>{{end}}`)},
+			"page.tmpl": {Data: []byte(`{{template "base" .}}{{define "body"}}v1{{end}}`)},
+		}
+		r := New()
+		r.SetHTMLLayouts("base.tmpl")
+		r.LoadHTMLFS(fsys, "*.tmpl")
+		r.GET("/", func(c *Context) {
+			c.HTML(http.StatusOK, "page.tmpl", nil)
+		})
+		r.GET("/missing", func(c *Context) {
+			c.HTML(http.StatusOK, "missing.tmpl", nil)
+		})
+		fsys["page.tmpl"] = &fstest.MapFile{Data: []byte(`{{template "base" .}}{{define "body"}}v2{{end}}`)}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Errorf("%s: got %d %q, want %q", mode, w.Code, w.Body.String(), want)
+		}
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("%s: missing template got %d, want 500", mode, w.Code)
+		}
+	}
+}
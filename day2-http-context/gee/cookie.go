@@ -0,0 +1,187 @@
+package gee
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrInvalidCookie is returned for a signed or encrypted cookie that
+	// was tampered with or issued under an unknown key
+	ErrInvalidCookie = errors.New("gee: invalid cookie")
+	// ErrNoCookieKeys is returned when Engine.SetCookieKeys was never called
+	ErrNoCookieKeys = errors.New("gee: cookie keys are not set")
+)
+
+// cookieKey holds the keys derived from one secret passed to SetCookieKeys
+type cookieKey struct {
+	sign    []byte
+	encrypt cipher.AEAD
+}
+
+// SetCookieKeys sets the secrets of signed and encrypted cookies. The
+// first secret issues new cookies, all of them are accepted when reading,
+// so a key is rotated by prepending the new secret.
+func (engine *Engine) SetCookieKeys(secrets ...[]byte) {
+	keys := make([]cookieKey, 0, len(secrets))
+	for _, secret := range secrets {
+		block, err := aes.NewCipher(deriveKey(secret, "gee cookie encryption"))
+		if err != nil {
+			panic(err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			panic(err)
+		}
+		keys = append(keys, cookieKey{sign: deriveKey(secret, "gee cookie signing"), encrypt: aead})
+	}
+	engine.cookieKeys = keys
+}
+
+// deriveKey gives every purpose its own 32 byte key from one secret
+func deriveKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// Cookie returns the unescaped value of the request cookie name
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return url.QueryUnescape(cookie.Value)
+}
+
+// SetCookie adds a Set-Cookie header. An empty path means "/", SameSite
+// comes from Engine.SameSite, and SameSite=None cookies are always Secure
+// as browsers require. A negative maxAge deletes the cookie.
+func (c *Context) SetCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) {
+	if path == "" {
+		path = "/"
+	}
+	sameSite := http.SameSiteLaxMode
+	if c.engine != nil && c.engine.SameSite != 0 {
+		sameSite = c.engine.SameSite
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    url.QueryEscape(value),
+		MaxAge:   maxAge,
+		Path:     path,
+		Domain:   domain,
+		SameSite: sameSite,
+		Secure:   secure || sameSite == http.SameSiteNoneMode,
+		HttpOnly: httpOnly,
+	})
+}
+
+// SetSignedCookie sets a cookie readable by the client but protected
+// against changes by an HMAC-SHA256 signature
+func (c *Context) SetSignedCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) error {
+	keys, err := c.cookieKeys()
+	if err != nil {
+		return err
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value))
+	signature := base64.RawURLEncoding.EncodeToString(signCookie(keys[0].sign, name, payload))
+	c.SetCookie(name, payload+"."+signature, maxAge, path, domain, secure, httpOnly)
+	return nil
+}
+
+// SignedCookie returns the value of a cookie set by SetSignedCookie
+func (c *Context) SignedCookie(name string) (string, error) {
+	keys, err := c.cookieKeys()
+	if err != nil {
+		return "", err
+	}
+	raw, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	payload, signature, ok := strings.Cut(raw, ".")
+	if !ok {
+		return "", ErrInvalidCookie
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	for _, key := range keys {
+		if hmac.Equal(mac, signCookie(key.sign, name, payload)) {
+			value, err := base64.RawURLEncoding.DecodeString(payload)
+			if err != nil {
+				return "", ErrInvalidCookie
+			}
+			return string(value), nil
+		}
+	}
+	return "", ErrInvalidCookie
+}
+
+// SetEncryptedCookie sets a cookie whose value is hidden from the client
+// and authenticated with AES-GCM
+func (c *Context) SetEncryptedCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) error {
+	keys, err := c.cookieKeys()
+	if err != nil {
+		return err
+	}
+	aead := keys[0].encrypt
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	// the cookie name is authenticated too, so a value can't be moved to another cookie
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	c.SetCookie(name, base64.RawURLEncoding.EncodeToString(sealed), maxAge, path, domain, secure, httpOnly)
+	return nil
+}
+
+// EncryptedCookie returns the value of a cookie set by SetEncryptedCookie
+func (c *Context) EncryptedCookie(name string) (string, error) {
+	keys, err := c.cookieKeys()
+	if err != nil {
+		return "", err
+	}
+	raw, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	for _, key := range keys {
+		aead := key.encrypt
+		if len(sealed) < aead.NonceSize() {
+			return "", ErrInvalidCookie
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		if value, err := aead.Open(nil, nonce, ciphertext, []byte(name)); err == nil {
+			return string(value), nil
+		}
+	}
+	return "", ErrInvalidCookie
+}
+
+func (c *Context) cookieKeys() ([]cookieKey, error) {
+	if c.engine == nil || len(c.engine.cookieKeys) == 0 {
+		return nil, ErrNoCookieKeys
+	}
+	return c.engine.cookieKeys, nil
+}
+
+func signCookie(key []byte, name, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + "=" + payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,175 @@
+package gee
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newCookieEngine serves /set, storing the value query parameter in the
+// cookie "session", and /get, echoing it back or the read error
+func newCookieEngine(encrypted bool, secrets ...[]byte) *Engine {
+	r := New()
+	r.SetCookieKeys(secrets...)
+	r.GET("/set", func(c *Context) {
+		var err error
+		if encrypted {
+			err = c.SetEncryptedCookie("session", c.Query("value"), 3600, "", "", false, true)
+		} else {
+			err = c.SetSignedCookie("session", c.Query("value"), 3600, "", "", false, true)
+		}
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/get", func(c *Context) {
+		var value string
+		var err error
+		if encrypted {
+			value, err = c.EncryptedCookie("session")
+		} else {
+			value, err = c.SignedCookie("session")
+		}
+		if errors.Is(err, ErrInvalidCookie) {
+			c.String(http.StatusForbidden, err.Error())
+			return
+		}
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, value)
+	})
+	return r
+}
+
+// issueCookie returns the raw value of the cookie set by /set
+func issueCookie(t *testing.T, r *Engine, value string) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/set?value="+value, nil))
+	cookies := w.Result().Cookies()
+	if w.Code != http.StatusOK || len(cookies) != 1 {
+		t.Fatalf("/set got %d with cookies %v", w.Code, cookies)
+	}
+	return cookies[0].Value
+}
+
+// readCookie sends raw under name to /get
+func readCookie(r *Engine, name, raw string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/get", nil)
+	req.AddCookie(&http.Cookie{Name: name, Value: raw})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// flip changes the character at i into another valid base64url character
+func flip(s string, i int) string {
+	c := byte('A')
+	if s[i] == 'A' {
+		c = 'B'
+	}
+	return s[:i] + string(c) + s[i+1:]
+}
+
+func TestCookieRoundTrip(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		r := newCookieEngine(encrypted, []byte("secret"))
+		raw := issueCookie(t, r, "alice")
+		if encrypted && strings.Contains(raw, "YWxpY2U") {
+			t.Errorf("encrypted cookie %q exposes its value", raw)
+		}
+		if w := readCookie(r, "session", raw); w.Code != http.StatusOK || w.Body.String() != "alice" {
+			t.Errorf("encrypted=%v: got %d %q, want alice", encrypted, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestCookieTampered(t *testing.T) {
+	r := newCookieEngine(false, []byte("secret"))
+	raw := issueCookie(t, r, "alice")
+	payload, signature, _ := strings.Cut(raw, ".")
+	tests := map[string]string{
+		"payload":   flip(payload, 0) + "." + signature,
+		"signature": payload + "." + flip(signature, 0),
+		"unsigned":  payload,
+		"forged":    "YWRtaW4." + signature,
+	}
+	for name, value := range tests {
+		if w := readCookie(r, "session", value); w.Code != http.StatusForbidden {
+			t.Errorf("signed %s: got %d %q, want 403", name, w.Code, w.Body.String())
+		}
+	}
+
+	r = newCookieEngine(true, []byte("secret"))
+	raw = issueCookie(t, r, "alice")
+	for _, i := range []int{0, len(raw) / 2, len(raw) - 2} {
+		if w := readCookie(r, "session", flip(raw, i)); w.Code != http.StatusForbidden {
+			t.Errorf("encrypted byte %d changed: got %d %q, want 403", i, w.Code, w.Body.String())
+		}
+	}
+	if w := readCookie(r, "session", "AAAA"); w.Code != http.StatusForbidden {
+		t.Errorf("short encrypted cookie: got %d, want 403", w.Code)
+	}
+}
+
+func TestCookieRenamed(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		r := newCookieEngine(encrypted, []byte("secret"))
+		raw := issueCookie(t, r, "alice")
+		r.GET("/other", func(c *Context) {
+			var err error
+			if encrypted {
+				_, err = c.EncryptedCookie("other")
+			} else {
+				_, err = c.SignedCookie("other")
+			}
+			if !errors.Is(err, ErrInvalidCookie) {
+				t.Errorf("encrypted=%v: moved cookie got %v, want ErrInvalidCookie", encrypted, err)
+			}
+		})
+		req := httptest.NewRequest("GET", "/other", nil)
+		req.AddCookie(&http.Cookie{Name: "other", Value: raw})
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func TestCookieKeyRotation(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		old := newCookieEngine(encrypted, []byte("old secret"))
+		raw := issueCookie(t, old, "alice")
+
+		rotated := newCookieEngine(encrypted, []byte("new secret"), []byte("old secret"))
+		if w := readCookie(rotated, "session", raw); w.Code != http.StatusOK || w.Body.String() != "alice" {
+			t.Errorf("encrypted=%v: cookie of the old key got %d %q, want alice", encrypted, w.Code, w.Body.String())
+		}
+		// new cookies are issued under the first key only
+		fresh := issueCookie(t, rotated, "bob")
+		if w := readCookie(old, "session", fresh); w.Code != http.StatusForbidden {
+			t.Errorf("encrypted=%v: old key accepted a cookie of the new key, got %d", encrypted, w.Code)
+		}
+
+		retired := newCookieEngine(encrypted, []byte("new secret"))
+		if w := readCookie(retired, "session", raw); w.Code != http.StatusForbidden {
+			t.Errorf("encrypted=%v: retired key still accepted, got %d", encrypted, w.Code)
+		}
+	}
+}
+
+func TestCookieNoKeys(t *testing.T) {
+	r := New()
+	r.GET("/", func(c *Context) {
+		if err := c.SetSignedCookie("session", "alice", 0, "", "", false, false); !errors.Is(err, ErrNoCookieKeys) {
+			t.Errorf("SetSignedCookie = %v, want ErrNoCookieKeys", err)
+		}
+		if _, err := c.EncryptedCookie("session"); !errors.Is(err, ErrNoCookieKeys) {
+			t.Errorf("EncryptedCookie = %v, want ErrNoCookieKeys", err)
+		}
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
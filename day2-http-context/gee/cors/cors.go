@@ -0,0 +1,215 @@
+// Package cors implements Cross-Origin Resource Sharing for gee.
+//
+//	r.Use(cors.New(cors.Config{
+//		AllowOrigins:     []string{"https://app.example.com", "https://*.example.com"},
+//		AllowCredentials: true,
+//		MaxAge:           time.Hour,
+//	}))
+//
+// The middleware answers preflight requests itself, so no OPTIONS routes
+// are needed. Register it with Engine.Use so it also runs for them.
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gee"
+)
+
+// Config configures New
+type Config struct {
+	// AllowOrigins are the origins allowed, "*" allows any origin and
+	// "https://*.example.com" any subdomain of example.com
+	AllowOrigins []string
+	// AllowOriginFunc is asked about origins not in AllowOrigins
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods defaults to GET, HEAD, POST, PUT, PATCH and DELETE
+	AllowMethods []string
+	// AllowHeaders are the request headers allowed, by default
+	// Origin, Accept, Content-Type and Authorization
+	AllowHeaders []string
+	// ExposeHeaders are the response headers scripts may read
+	ExposeHeaders []string
+	// AllowCredentials lets requests carry cookies and HTTP authentication
+	AllowCredentials bool
+	// MaxAge is how long preflight results may be cached, 0 leaves it to
+	// the browser
+	MaxAge time.Duration
+}
+
+// Default allows any origin without credentials
+func Default() gee.HandlerFunc {
+	return New(Config{AllowOrigins: []string{"*"}})
+}
+
+// Validate reports a configuration that browsers would reject, such as
+// "*" combined with credentials
+func (config Config) Validate() error {
+	if len(config.AllowOrigins) == 0 && config.AllowOriginFunc == nil {
+		return errors.New("cors: no origin allowed, set AllowOrigins or AllowOriginFunc")
+	}
+	for _, origin := range config.AllowOrigins {
+		switch {
+		case origin == "*":
+			if config.AllowCredentials {
+				return errors.New(`cors: AllowOrigins "*" can't be combined with AllowCredentials`)
+			}
+		case origin == "null":
+		case !strings.Contains(origin, "://"):
+			return fmt.Errorf("cors: origin %q has no scheme", origin)
+		case strings.Count(origin, "*") > 1:
+			return fmt.Errorf("cors: origin %q has more than one wildcard", origin)
+		case strings.Contains(origin, "*") && !strings.Contains(origin, "://*."):
+			return fmt.Errorf("cors: origin %q must put the wildcard on a subdomain, e.g. https://*.example.com", origin)
+		}
+	}
+	return nil
+}
+
+type cors struct {
+	allowAll      bool
+	origins       map[string]bool
+	wildcards     [][2]string
+	originFunc    func(string) bool
+	methods       map[string]bool
+	headers       map[string]bool
+	allowMethods  string
+	allowHeaders  string
+	exposeHeaders string
+	credentials   bool
+	maxAge        string
+}
+
+// New returns the CORS middleware, it panics on an invalid config
+func New(config Config) gee.HandlerFunc {
+	if err := config.Validate(); err != nil {
+		panic(err)
+	}
+	if len(config.AllowMethods) == 0 {
+		config.AllowMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+	}
+	if len(config.AllowHeaders) == 0 {
+		config.AllowHeaders = []string{"Origin", "Accept", "Content-Type", "Authorization"}
+	}
+	c := &cors{
+		origins:       map[string]bool{},
+		originFunc:    config.AllowOriginFunc,
+		methods:       map[string]bool{},
+		headers:       map[string]bool{},
+		allowMethods:  strings.Join(config.AllowMethods, ", "),
+		allowHeaders:  strings.Join(config.AllowHeaders, ", "),
+		exposeHeaders: strings.Join(config.ExposeHeaders, ", "),
+		credentials:   config.AllowCredentials,
+	}
+	for _, origin := range config.AllowOrigins {
+		origin = strings.ToLower(origin)
+		if origin == "*" {
+			c.allowAll = true
+		} else if prefix, suffix, ok := strings.Cut(origin, "*"); ok {
+			c.wildcards = append(c.wildcards, [2]string{prefix, suffix})
+		} else {
+			c.origins[origin] = true
+		}
+	}
+	for _, method := range config.AllowMethods {
+		c.methods[strings.ToUpper(method)] = true
+	}
+	for _, header := range config.AllowHeaders {
+		c.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	if config.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(config.MaxAge / time.Second))
+	}
+	return c.handle
+}
+
+func (c *cors) handle(ctx *gee.Context) {
+	header := ctx.Writer.Header()
+	origin := ctx.Req.Header.Get("Origin")
+	preflight := ctx.Req.Method == http.MethodOptions && ctx.Req.Header.Get("Access-Control-Request-Method") != ""
+	if !c.allowAll {
+		// the response depends on the origin, caches must key on it
+		header.Add("Vary", "Origin")
+	}
+	if preflight {
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+	}
+	if origin == "" {
+		ctx.Next()
+		return
+	}
+	if !c.allowOrigin(origin) {
+		if preflight {
+			ctx.Status(http.StatusForbidden)
+			ctx.Abort()
+			return
+		}
+		// without CORS headers the browser hides the response
+		ctx.Next()
+		return
+	}
+	if c.allowAll {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		if c.exposeHeaders != "" {
+			header.Set("Access-Control-Expose-Headers", c.exposeHeaders)
+		}
+		ctx.Next()
+		return
+	}
+	if !c.methods[strings.ToUpper(ctx.Req.Header.Get("Access-Control-Request-Method"))] || !c.allowRequestHeaders(ctx.Req) {
+		header.Del("Access-Control-Allow-Origin")
+		header.Del("Access-Control-Allow-Credentials")
+		ctx.Status(http.StatusForbidden)
+		ctx.Abort()
+		return
+	}
+	header.Set("Access-Control-Allow-Methods", c.allowMethods)
+	header.Set("Access-Control-Allow-Headers", c.allowHeaders)
+	if c.maxAge != "" {
+		header.Set("Access-Control-Max-Age", c.maxAge)
+	}
+	ctx.Status(http.StatusNoContent)
+	ctx.Abort()
+}
+
+func (c *cors) allowOrigin(origin string) bool {
+	if c.allowAll {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	if c.origins[lower] {
+		return true
+	}
+	for _, w := range c.wildcards {
+		if len(lower) > len(w[0])+len(w[1]) && strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) {
+			return true
+		}
+	}
+	return c.originFunc != nil && c.originFunc(origin)
+}
+
+// allowRequestHeaders reports whether every header of
+// Access-Control-Request-Headers is allowed
+func (c *cors) allowRequestHeaders(req *http.Request) bool {
+	for _, value := range req.Header.Values("Access-Control-Request-Headers") {
+		for _, h := range strings.Split(value, ",") {
+			if h = strings.TrimSpace(h); h != "" && !c.headers[http.CanonicalHeaderKey(h)] {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,200 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"gee"
+)
+
+func init() {
+	gee.SetMode(gee.TestMode)
+}
+
+// newEngine guards GET /api with config and counts the handler calls
+func newEngine(config Config, calls *int) *gee.Engine {
+	r := gee.New()
+	r.Use(New(config))
+	r.GET("/api", func(c *gee.Context) {
+		*calls++
+		c.String(http.StatusOK, "data")
+	})
+	return r
+}
+
+func request(r *gee.Engine, method, origin string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api", nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func preflight(method string, headers string) http.Header {
+	header := http.Header{"Access-Control-Request-Method": {method}}
+	if headers != "" {
+		header.Set("Access-Control-Request-Headers", headers)
+	}
+	return header
+}
+
+func TestPreflight(t *testing.T) {
+	calls := 0
+	r := newEngine(Config{
+		AllowOrigins:     []string{"https://app.example.com"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}, &calls)
+
+	w := request(r, "OPTIONS", "https://app.example.com", preflight("PUT", "content-type, authorization"))
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Fatalf("got %d %q, want an empty 204", w.Code, w.Body.String())
+	}
+	want := map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, HEAD, POST, PUT, PATCH, DELETE",
+		"Access-Control-Allow-Headers":     "Origin, Accept, Content-Type, Authorization",
+		"Access-Control-Max-Age":           "3600",
+	}
+	for name, value := range want {
+		if got := w.Header().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if calls != 0 {
+		t.Errorf("the handler ran for a preflight")
+	}
+
+	rejected := map[string]http.Header{
+		"method": preflight("TRACE", ""),
+		"header": preflight("PUT", "X-Secret"),
+	}
+	for name, header := range rejected {
+		w := request(r, "OPTIONS", "https://app.example.com", header)
+		if w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("disallowed %s: got %d with origin %q", name, w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+		}
+	}
+	if w := request(r, "OPTIONS", "https://evil.example", preflight("GET", "")); w.Code != http.StatusForbidden {
+		t.Errorf("foreign origin preflight got %d, want 403", w.Code)
+	}
+	if calls != 0 {
+		t.Errorf("the handler ran for a rejected preflight")
+	}
+}
+
+func TestActualRequest(t *testing.T) {
+	calls := 0
+	r := newEngine(Config{
+		AllowOrigins:  []string{"https://app.example.com"},
+		ExposeHeaders: []string{"X-Total"},
+	}, &calls)
+
+	w := request(r, "GET", "https://app.example.com", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("got %d with origin %q", w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != "X-Total" {
+		t.Errorf("Access-Control-Expose-Headers = %q", got)
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("credentials allowed without AllowCredentials")
+	}
+
+	// the browser hides the response, the server still answers
+	w = request(r, "GET", "https://evil.example", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("foreign origin got %d with origin %q", w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want 2", calls)
+	}
+}
+
+func TestVaryOrigin(t *testing.T) {
+	calls := 0
+	specific := newEngine(Config{AllowOrigins: []string{"https://app.example.com"}}, &calls)
+	for _, origin := range []string{"https://app.example.com", "https://evil.example", ""} {
+		w := request(specific, "GET", origin, nil)
+		if !slices.Contains(w.Header().Values("Vary"), "Origin") {
+			t.Errorf("origin %q: Vary = %v, want Origin", origin, w.Header().Values("Vary"))
+		}
+	}
+	w := request(specific, "OPTIONS", "https://app.example.com", preflight("GET", ""))
+	for _, v := range []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"} {
+		if !slices.Contains(w.Header().Values("Vary"), v) {
+			t.Errorf("preflight Vary = %v, want %s", w.Header().Values("Vary"), v)
+		}
+	}
+
+	any := newEngine(Config{AllowOrigins: []string{"*"}}, &calls)
+	w = request(any, "GET", "https://app.example.com", nil)
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" || slices.Contains(w.Header().Values("Vary"), "Origin") {
+		t.Errorf("* got origin %q and Vary %v", w.Header().Get("Access-Control-Allow-Origin"), w.Header().Values("Vary"))
+	}
+}
+
+func TestWildcardSubdomain(t *testing.T) {
+	calls := 0
+	r := newEngine(Config{AllowOrigins: []string{"https://*.example.com"}}, &calls)
+	tests := map[string]bool{
+		"https://app.example.com":     true,
+		"https://a.b.example.com":     true,
+		"https://APP.Example.com":     true,
+		"https://example.com":         false,
+		"https://.example.com":        false,
+		"http://app.example.com":      false,
+		"https://evil-example.com":    false,
+		"https://example.com.evil.io": false,
+	}
+	for origin, allowed := range tests {
+		w := request(r, "GET", origin, nil)
+		if got := w.Header().Get("Access-Control-Allow-Origin") == origin; got != allowed {
+			t.Errorf("%s: allowed = %v, want %v", origin, got, allowed)
+		}
+	}
+}
+
+func TestAllowOriginFunc(t *testing.T) {
+	calls := 0
+	r := newEngine(Config{AllowOriginFunc: func(origin string) bool { return origin == "https://dev.local" }}, &calls)
+	if w := request(r, "GET", "https://dev.local", nil); w.Header().Get("Access-Control-Allow-Origin") != "https://dev.local" {
+		t.Errorf("AllowOriginFunc was not asked")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		config Config
+		valid  bool
+	}{
+		{Config{AllowOrigins: []string{"*"}}, true},
+		{Config{AllowOrigins: []string{"*"}, AllowCredentials: true}, false},
+		{Config{AllowOrigins: []string{"https://*.example.com"}, AllowCredentials: true}, true},
+		{Config{}, false},
+		{Config{AllowOrigins: []string{"example.com"}}, false},
+		{Config{AllowOrigins: []string{"https://*.*.example.com"}}, false},
+		{Config{AllowOrigins: []string{"https://example.*"}}, false},
+		{Config{AllowOrigins: []string{"null"}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.config.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%+v: Validate = %v, want valid %v", tt.config, err, tt.valid)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf(`New accepted "*" with credentials`)
+		}
+	}()
+	New(Config{AllowOrigins: []string{"*"}, AllowCredentials: true})
+}
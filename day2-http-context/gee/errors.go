@@ -0,0 +1,206 @@
+package gee
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// ErrorType classifies the errors collected by Context.Error
+type ErrorType uint64
+
+const (
+	// ErrorTypeBind is an error decoding the request, answered with 400
+	ErrorTypeBind ErrorType = 1 << iota
+	// ErrorTypePrivate is logged but never shown to the client
+	ErrorTypePrivate
+	// ErrorTypePublic has a message meant for the client
+	ErrorTypePublic
+	// ErrorTypeAny matches every type
+	ErrorTypeAny ErrorType = 1<<64 - 1
+)
+
+// Error is an error collected on the Context
+type Error struct {
+	Err  error
+	Type ErrorType
+	// Status is the response code, 0 picks one from Type
+	Status int
+	Meta   interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// SetType sets the type of the error
+func (e *Error) SetType(t ErrorType) *Error {
+	e.Type = t
+	return e
+}
+
+// SetStatus sets the response code of the error
+func (e *Error) SetStatus(code int) *Error {
+	e.Status = code
+	return e
+}
+
+// SetMeta attaches data to the error, an H is merged into its JSON body
+func (e *Error) SetMeta(meta interface{}) *Error {
+	e.Meta = meta
+	return e
+}
+
+// IsType reports whether the error has one of the types in flags
+func (e *Error) IsType(flags ErrorType) bool {
+	return e.Type&flags > 0
+}
+
+// StatusCode returns Status, or 400 for bind errors and 500 otherwise
+func (e *Error) StatusCode() int {
+	switch {
+	case e.Status != 0:
+		return e.Status
+	case e.IsType(ErrorTypeBind):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// JSON returns the body showing the error to the client
+func (e *Error) JSON() H {
+	body := H{}
+	if meta, ok := e.Meta.(H); ok {
+		for k, v := range meta {
+			body[k] = v
+		}
+	}
+	body["error"] = e.Error()
+	return body
+}
+
+// Errors are the errors collected on a Context
+type Errors []*Error
+
+// ByType returns the errors with one of the types in flags
+func (errs Errors) ByType(flags ErrorType) Errors {
+	var result Errors
+	for _, e := range errs {
+		if e.IsType(flags) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Last returns the last error, or nil
+func (errs Errors) Last() *Error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs[len(errs)-1]
+}
+
+func (errs Errors) String() string {
+	var b strings.Builder
+	for i, e := range errs {
+		fmt.Fprintf(&b, "Error #%02d: %s\n", i+1, e.Err)
+	}
+	return b.String()
+}
+
+// Error adds err to c.Errors, private unless err already is an *Error or
+// a Problem, and returns it so the type can be changed. The Engine ErrorHandler
+// renders the collected errors once the chain is done.
+func (c *Context) Error(err error) *Error {
+	if err == nil {
+		panic("gee: c.Error(nil)")
+	}
+	var (
+		e *Error
+		p Problem
+	)
+	switch {
+	case errors.As(err, &e):
+	case errors.As(err, &p):
+		e = &Error{Err: err, Type: ErrorTypePublic, Status: p.Status}
+	default:
+		e = &Error{Err: err, Type: ErrorTypePrivate}
+	}
+	// a HandlerFuncE may return the result of c.Error
+	for _, added := range c.Errors {
+		if added == e {
+			return e
+		}
+	}
+	c.Errors = append(c.Errors, e)
+	return e
+}
+
+// HandlerFuncE is a handler returning its error instead of writing it
+type HandlerFuncE func(*Context) error
+
+// WrapE adapts h to a HandlerFunc, a returned error is passed to c.Error
+// and aborts the chain
+func WrapE(h HandlerFuncE) HandlerFunc {
+	return func(c *Context) {
+		if err := h(c); err != nil {
+			c.Error(err)
+			c.Abort()
+		}
+	}
+}
+
+// HandleE registers a HandlerFuncE for method and pattern
+func (engine *Engine) HandleE(method string, pattern string, handler HandlerFuncE) *Route {
+	return engine.addRoute(method, pattern, WrapE(handler))
+}
+
+// DefaultErrorHandler logs the private errors not logged yet and, unless
+// the response was written already, answers with the last error, as a
+// Problem when it is one or the engine is configured for it. Private errors
+// only show the status text to the client. Nothing is logged in TestMode
+// unless the engine has a logger set with SetLogger.
+func DefaultErrorHandler(c *Context) {
+	if c.loggedErrors < len(c.Errors) {
+		if Mode() != TestMode || c.engine != nil && c.engine.logger != nil {
+			for _, e := range c.Errors[c.loggedErrors:].ByType(ErrorTypePrivate) {
+				c.Logger().Error("handler error", slog.String("error", e.Error()))
+			}
+		}
+		c.loggedErrors = len(c.Errors)
+	}
+	if c.Written() {
+		return
+	}
+	last := c.Errors.Last()
+	code := last.StatusCode()
+	var problem Problem
+	if errors.As(last.Err, &problem) {
+		c.Problem(problem)
+		return
+	}
+	if c.engine != nil && c.engine.ProblemDetails {
+		p := Problem{Status: code, Instance: c.Req.URL.Path}
+		if last.IsType(ErrorTypePublic | ErrorTypeBind) {
+			p.Detail = last.Error()
+			if meta, ok := last.Meta.(H); ok {
+				p.Extensions = meta
+			}
+		}
+		c.Problem(p)
+		return
+	}
+	if last.IsType(ErrorTypePublic | ErrorTypeBind) {
+		c.JSON(code, last.JSON())
+		return
+	}
+	c.JSON(code, H{"error": http.StatusText(code)})
+}
@@ -0,0 +1,77 @@
+package gee
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorAfterWrite(t *testing.T) {
+	raw := New()
+	raw.GET("/", func(c *Context) {
+		// written without c.Status, c.StatusCode stays 0
+		c.Writer.Write([]byte("partial"))
+		c.Error(errors.New("late failure"))
+	})
+	buffered := New()
+	buffered.Use(AutoETag())
+	buffered.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+		c.Error(errors.New("late failure"))
+	})
+	for want, r := range map[string]*Engine{"partial": raw, "ok": buffered} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Errorf("got %d %q, want the handler response %q alone", w.Code, w.Body.String(), want)
+		}
+	}
+}
+
+func TestNilErrorHandler(t *testing.T) {
+	r := New()
+	r.ErrorHandler = nil
+	r.GET("/", func(c *Context) {
+		c.Error(errors.New("db down"))
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "db down") {
+		t.Errorf("got %d %q, want a 500 hiding the private error", w.Code, w.Body.String())
+	}
+}
+
+func TestPrivateErrorsLoggedOnce(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	r.Use(func(c *Context) {
+		c.Next()
+		c.Error(errors.New("second"))
+	})
+	r.GET("/", func(c *Context) {
+		c.Error(errors.New("first"))
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if n := strings.Count(buf.String(), "handler error"); n != 2 {
+		t.Errorf("got %d log records, want one per error:\n%s", n, buf.String())
+	}
+}
+
+func TestPrivateErrorsSilentInTestMode(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	r := New()
+	r.GET("/", func(c *Context) {
+		c.Error(errors.New("db down"))
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if buf.Len() != 0 {
+		t.Errorf("logged in TestMode:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,100 @@
+package gee
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"gee/render"
+)
+
+// File writes the local file filepath. Range and conditional requests are
+// answered by http.ServeContent.
+func (c *Context) File(filepath string) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		notFound(c)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		notFound(c)
+		return
+	}
+	http.ServeContent(c.Writer, c.Req, info.Name(), info.ModTime(), f)
+}
+
+// FileAttachment writes the local file filepath so that browsers save it
+// as filename
+func (c *Context) FileAttachment(filepath, filename string) {
+	c.SetHeader("Content-Disposition", contentDisposition("attachment", filename))
+	c.File(filepath)
+}
+
+// DataFromReader streams reader to the client. contentLength may be -1
+// when unknown, extraHeaders are set before the status is written. When
+// reading fails midway the connection is aborted, so the client sees a
+// broken download instead of a truncated one that looks complete.
+func (c *Context) DataFromReader(code int, contentLength int64, contentType string, reader io.Reader, extraHeaders map[string]string) {
+	for key, value := range extraHeaders {
+		c.SetHeader(key, value)
+	}
+	if contentLength >= 0 {
+		c.SetHeader("Content-Length", strconv.FormatInt(contentLength, 10))
+	}
+	r := render.Reader{ContentType: contentType, Reader: reader}
+	r.WriteContentType(c.Writer)
+	c.Status(code)
+	if !bodyAllowedForStatus(code) {
+		return
+	}
+	if err := r.Render(c.Writer); err != nil {
+		// the status is sent already, only the connection is left to tell
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// contentDisposition builds an RFC 6266 header value with a plain ASCII
+// filename for old clients and the exact UTF-8 name in filename*
+func contentDisposition(dispositionType, filename string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return '_'
+		}
+		return r
+	}, filename)
+	fallback = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(fallback)
+	value := dispositionType + `; filename="` + fallback + `"`
+	if fallback != filename {
+		value += "; filename*=UTF-8''" + encodeExtValue(filename)
+	}
+	return value
+}
+
+// encodeExtValue percent-encodes s as an RFC 8187 ext-value
+func encodeExtValue(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if isAttrChar(ch) {
+			b.WriteByte(ch)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[ch>>4])
+		b.WriteByte(hex[ch&0x0f])
+	}
+	return b.String()
+}
+
+func isAttrChar(ch byte) bool {
+	switch {
+	case 'a' <= ch && ch <= 'z', 'A' <= ch && ch <= 'Z', '0' <= ch && ch <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", ch) >= 0
+}
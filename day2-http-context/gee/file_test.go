@@ -0,0 +1,162 @@
+package gee
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// failingReader returns data, then err
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestDataFromReader(t *testing.T) {
+	r := New()
+	r.GET("/ok", func(c *Context) {
+		c.DataFromReader(http.StatusOK, 5, "text/plain", strings.NewReader("hello"),
+			map[string]string{"Content-Disposition": `attachment; filename="a.txt"`})
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/ok", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "hello" {
+		t.Fatalf("got %d %q", w.Code, w.Body.String())
+	}
+	for name, want := range map[string]string{
+		"Content-Type":        "text/plain",
+		"Content-Length":      "5",
+		"Content-Disposition": `attachment; filename="a.txt"`,
+	} {
+		if got := w.Header().Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestDataFromReaderFailure(t *testing.T) {
+	for _, length := range []int64{10, -1} {
+		r := New()
+		r.GET("/", func(c *Context) {
+			reader := &failingReader{data: "hello", err: errors.New("disk failure")}
+			c.DataFromReader(http.StatusOK, length, "text/plain", reader, nil)
+		})
+		ts := httptest.NewServer(r)
+		// a small body may fail before the response even reaches the client
+		resp, err := http.Get(ts.URL)
+		var body []byte
+		if err == nil {
+			body, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		ts.Close()
+		if err == nil {
+			t.Errorf("length %d: the download looked complete: %q", length, body)
+		}
+		if strings.Contains(string(body), "disk failure") {
+			t.Errorf("length %d: error text in the body: %q", length, body)
+		}
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"report.pdf", `attachment; filename="report.pdf"`},
+		{"my report.pdf", `attachment; filename="my report.pdf"`},
+		{"résumé.pdf", `attachment; filename="r_sum_.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9.pdf`},
+		{"数据.csv", `attachment; filename="__.csv"; filename*=UTF-8''%E6%95%B0%E6%8D%AE.csv`},
+		{`a"b\c.txt`, `attachment; filename="a\"b\\c.txt"; filename*=UTF-8''a%22b%5Cc.txt`},
+		{"a\r\nb.txt", `attachment; filename="a__b.txt"; filename*=UTF-8''a%0D%0Ab.txt`},
+		{"100%;x'.txt", `attachment; filename="100%;x'.txt"`},
+	}
+	for _, tt := range tests {
+		if got := contentDisposition("attachment", tt.name); got != tt.want {
+			t.Errorf("%q:\ngot  %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeExtValue(t *testing.T) {
+	tests := map[string]string{
+		"plain-name_1.txt": "plain-name_1.txt",
+		"a b":              "a%20b",
+		"100%":             "100%25",
+		"it's;x":           "it%27s%3Bx",
+		"é":                "%C3%A9",
+		"😀":                "%F0%9F%98%80",
+		"!#$&+-.^_`|~":     "!#$&+-.^_`|~",
+	}
+	for in, want := range tests {
+		if got := encodeExtValue(in); got != want {
+			t.Errorf("encodeExtValue(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(path, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.GET("/file", func(c *Context) {
+		c.File(path)
+	})
+	r.GET("/missing", func(c *Context) {
+		c.File(filepath.Join(dir, "missing.txt"))
+	})
+	r.GET("/dir", func(c *Context) {
+		c.File(dir)
+	})
+	r.GET("/download", func(c *Context) {
+		c.FileAttachment(path, "報告 1.txt")
+	})
+
+	w := get(r, "/file", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" {
+		t.Errorf("got %d %q", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if w.Header().Get("Content-Disposition") != "" {
+		t.Errorf("File set Content-Disposition %q", w.Header().Get("Content-Disposition"))
+	}
+	w = get(r, "/file", map[string]string{"Range": "bytes=-3"})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "789" {
+		t.Errorf("range got %d %q", w.Code, w.Body.String())
+	}
+	lastModified := w.Header().Get("Last-Modified")
+	if w := get(r, "/file", map[string]string{"If-Modified-Since": lastModified}); w.Code != http.StatusNotModified {
+		t.Errorf("If-Modified-Since got %d, want 304", w.Code)
+	}
+	for _, path := range []string{"/missing", "/dir"} {
+		if w := get(r, path, nil); w.Code != http.StatusNotFound {
+			t.Errorf("%s: got %d, want 404", path, w.Code)
+		}
+	}
+
+	w = get(r, "/download", nil)
+	want := `attachment; filename="__ 1.txt"; filename*=UTF-8''%E5%A0%B1%E5%91%8A%201.txt`
+	if w.Code != http.StatusOK || w.Header().Get("Content-Disposition") != want {
+		t.Errorf("got %d with Content-Disposition %q, want %q", w.Code, w.Header().Get("Content-Disposition"), want)
+	}
+}
@@ -1,8 +1,16 @@
 package gee
 
 import (
-	"log"
+	"context"
+	"errors"
+	"html/template"
+	"io/fs"
+	"log/slog"
 	"net/http"
+	"net/netip"
+	"sync"
+
+	"gee/render"
 )
 
 // HandlerFunc defines the request handler used by gee
@@ -10,35 +18,245 @@ type HandlerFunc func(*Context)
 
 // Engine implement the interface of ServeHTTP
 type Engine struct {
-	router *router
+	router      *router
+	middlewares []HandlerFunc
+	namedRoutes map[string]*Route
+	routes      []RouteInfo
+	// SPAExcludes are path prefixes that never fall back to the StaticSPA index
+	SPAExcludes []string
+	// SameSite is the SameSite attribute of cookies set by Context.SetCookie
+	SameSite   http.SameSite
+	cookieKeys []cookieKey
+	// RemoteIPHeaders are read by Context.ClientIP behind trusted proxies,
+	// in order. The default is X-Forwarded-For then X-Real-IP. Add
+	// "Forwarded" only when the proxies overwrite it, most of them pass a
+	// client supplied Forwarded header through untouched.
+	RemoteIPHeaders []string
+	// TrustedPlatform names a header set by the hosting platform that
+	// always carries the client IP, e.g. PlatformCloudflare
+	TrustedPlatform string
+	trustedProxies  []netip.Prefix
+	// HandleMethodNotAllowed answers 405 with an Allow header, instead of
+	// 404, when the path has routes for other methods only
+	HandleMethodNotAllowed bool
+	// ProblemDetails makes the default 404, 405 and error responses RFC 9457
+	// problem details, see Context.Problem
+	ProblemDetails bool
+	// MaxBodyBytes limits the body read by the binder, 0 means no limit
+	MaxBodyBytes int64
+	// ErrorHandler renders the errors collected by Context.Error once the
+	// handlers are done, DefaultErrorHandler by default or when nil
+	ErrorHandler HandlerFunc
+	// HTMLRender renders the templates loaded by the LoadHTML* methods
+	HTMLRender render.HTMLRender
+	delims     render.Delims
+	funcMap    template.FuncMap
+	layouts    []string
+	logger     *slog.Logger
+
+	mu         sync.Mutex
+	server     *http.Server
+	onShutdown []func(ctx context.Context) error
 }
 
 // New is the constructor of gee.Engine
 func New() *Engine {
-	return &Engine{router: newRouter()}
+	debugPrintWARNING()
+	return &Engine{
+		router:          newRouter(),
+		SPAExcludes:     []string{"/api/"},
+		SameSite:        http.SameSiteLaxMode,
+		RemoteIPHeaders: []string{"X-Forwarded-For", "X-Real-IP"},
+		ErrorHandler:    DefaultErrorHandler,
+	}
 }
 
-func (engine *Engine) addRoute(method string, pattern string, handler HandlerFunc) {
-	log.Printf("Route %4s - %s", method, pattern)
+func (engine *Engine) addRoute(method string, pattern string, handler HandlerFunc) *Route {
+	info := RouteInfo{Method: method, Path: pattern, Handler: nameOfFunction(handler)}
+	if IsDebugging() {
+		if engine.logger != nil {
+			engine.logger.Debug("route", slog.String("method", method), slog.String("pattern", pattern),
+				slog.String("handler", info.Handler), slog.Int("middlewares", len(engine.middlewares)))
+		} else {
+			debugPrint("%-6s %-25s --> %s (%d middlewares)", method, pattern, info.Handler, len(engine.middlewares))
+		}
+	}
+	engine.routes = append(engine.routes, info)
 	engine.router.addRoute(method, pattern, handler)
+	return &Route{Method: method, Pattern: pattern, engine: engine}
+}
+
+// RouteInfo describes a registered route
+type RouteInfo struct {
+	Method  string
+	Path    string
+	Handler string
+}
+
+// Routes returns the route table in registration order
+func (engine *Engine) Routes() []RouteInfo {
+	return append([]RouteInfo(nil), engine.routes...)
 }
 
 // GET defines the method to add GET request
-func (engine *Engine) GET(pattern string, handler HandlerFunc) {
-	engine.addRoute("GET", pattern, handler)
+func (engine *Engine) GET(pattern string, handler HandlerFunc) *Route {
+	return engine.addRoute("GET", pattern, handler)
 }
 
 // POST defines the method to add POST request
-func (engine *Engine) POST(pattern string, handler HandlerFunc) {
-	engine.addRoute("POST", pattern, handler)
+func (engine *Engine) POST(pattern string, handler HandlerFunc) *Route {
+	return engine.addRoute("POST", pattern, handler)
+}
+
+// HandleContext dispatches c again through the middlewares and the router,
+// typically after rewriting c.Req.URL.Path, without a client round-trip
+func (engine *Engine) HandleContext(c *Context) {
+	oldHandlers, oldIndex := c.handlers, c.index
+	c.Path = c.Req.URL.Path
+	c.Method = c.Req.Method
+	c.Params = nil
+	c.Keys = nil
+	c.fullPath = ""
+	c.logger = nil
+	c.handlers = append([]HandlerFunc(nil), engine.middlewares...)
+	c.index = -1
+	engine.router.handle(c)
+	c.handlers, c.index = oldHandlers, oldIndex
 }
 
-// Run defines the method to start a http server
+// Use adds middlewares run, in order, before the handler of every request
+func (engine *Engine) Use(middlewares ...HandlerFunc) {
+	engine.middlewares = append(engine.middlewares, middlewares...)
+}
+
+// NoRoute sets the handler for requests that match no route, 404 by default
+func (engine *Engine) NoRoute(handler HandlerFunc) {
+	engine.router.noRoute = handler
+}
+
+// Delims sets the template delimiters, call it before the LoadHTML* methods
+func (engine *Engine) Delims(left, right string) *Engine {
+	engine.delims = render.Delims{Left: left, Right: right}
+	return engine
+}
+
+// SetFuncMap sets the functions available to templates
+func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
+	engine.funcMap = funcMap
+}
+
+// SetHTMLLayouts sets the glob patterns of layouts and partials, every page
+// is parsed on top of them and may override their {{block}}s
+func (engine *Engine) SetHTMLLayouts(patterns ...string) {
+	engine.layouts = patterns
+}
+
+// LoadHTMLGlob loads the page templates matching pattern
+func (engine *Engine) LoadHTMLGlob(pattern string) {
+	engine.loadHTML(render.HTMLLoader{Patterns: []string{pattern}})
+}
+
+// LoadHTMLFiles loads the given page templates
+func (engine *Engine) LoadHTMLFiles(files ...string) {
+	engine.loadHTML(render.HTMLLoader{Files: files})
+}
+
+// LoadHTMLFS loads the page templates of fsys matching patterns,
+// layouts are looked up in fsys as well
+func (engine *Engine) LoadHTMLFS(fsys fs.FS, patterns ...string) {
+	engine.loadHTML(render.HTMLLoader{FS: fsys, Patterns: patterns})
+}
+
+func (engine *Engine) loadHTML(loader render.HTMLLoader) {
+	loader.Layouts = engine.layouts
+	loader.Delims = engine.delims
+	loader.FuncMap = engine.funcMap
+	templates, err := loader.Load()
+	if err != nil {
+		panic(err)
+	}
+	if IsDebugging() {
+		debugPrint("Loaded HTML templates, reloading them on every render")
+		engine.HTMLRender = render.HTMLDebug{Loader: loader}
+		return
+	}
+	engine.HTMLRender = render.HTMLProduction{Templates: templates}
+}
+
+// Run defines the method to start a http server, it returns
+// http.ErrServerClosed once Shutdown is called
 func (engine *Engine) Run(addr string) (err error) {
-	return http.ListenAndServe(addr, engine)
+	debugPrint("Listening and serving HTTP on %s", addr)
+	server := &http.Server{Addr: addr, Handler: engine}
+	engine.mu.Lock()
+	engine.server = server
+	engine.mu.Unlock()
+	return server.ListenAndServe()
+}
+
+// OnShutdown registers f to run on Shutdown, e.g. to drain hijacked
+// connections that http.Server does not track
+func (engine *Engine) OnShutdown(f func(ctx context.Context) error) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	engine.onShutdown = append(engine.onShutdown, f)
+}
+
+// Shutdown gracefully stops the server started by Run and runs the
+// OnShutdown hooks, waiting for all of them until ctx is done
+func (engine *Engine) Shutdown(ctx context.Context) error {
+	engine.mu.Lock()
+	server := engine.server
+	hooks := engine.onShutdown
+	engine.mu.Unlock()
+
+	errs := make(chan error, len(hooks)+1)
+	for _, hook := range hooks {
+		go func(hook func(ctx context.Context) error) {
+			errs <- hook(ctx)
+		}(hook)
+	}
+	if server != nil {
+		go func() {
+			errs <- server.Shutdown(ctx)
+		}()
+	} else {
+		errs <- nil
+	}
+
+	var err error
+	for i := 0; i < len(hooks)+1; i++ {
+		select {
+		case e := <-errs:
+			err = errors.Join(err, e)
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		}
+	}
+	return err
 }
 
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	c := newContext(w, req)
+	// the outermost writer tells Context.Written what reached the client
+	c := newContext(&responseWriter{ResponseWriter: w}, req)
+	c.handlers = append(c.handlers, engine.middlewares...)
+	c.engine = engine
+	c.assignRequestID()
 	engine.router.handle(c)
+	// errors added by middlewares after their call to Next
+	engine.handleErrors(c)
+}
+
+// handleErrors passes the errors added since the last call to ErrorHandler,
+// or to DefaultErrorHandler when none is set
+func (engine *Engine) handleErrors(c *Context) {
+	if len(c.Errors) <= c.handledErrors {
+		return
+	}
+	c.handledErrors = len(c.Errors)
+	handler := engine.ErrorHandler
+	if handler == nil {
+		handler = DefaultErrorHandler
+	}
+	handler(c)
 }
@@ -0,0 +1,308 @@
+package gee
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DefaultWriter is where Logger writes unless configured otherwise
+var DefaultWriter io.Writer = os.Stdout
+
+const (
+	green   = "\033[97;42m"
+	white   = "\033[90;47m"
+	yellow  = "\033[90;43m"
+	red     = "\033[97;41m"
+	blue    = "\033[97;44m"
+	magenta = "\033[97;45m"
+	cyan    = "\033[97;46m"
+	reset   = "\033[0m"
+)
+
+// LogParams describes a finished request to a LogFormatter
+type LogParams struct {
+	Request    *http.Request
+	TimeStamp  time.Time
+	StatusCode int
+	Latency    time.Duration
+	ClientIP   string
+	Method     string
+	Path       string
+	// BodySize is the number of bytes of the response body
+	BodySize int
+	Keys     map[string]interface{}
+	isTerm   bool
+}
+
+// StatusCodeColor returns the ANSI colour of the status code
+func (p *LogParams) StatusCodeColor() string {
+	switch code := p.StatusCode; {
+	case code >= 100 && code < 300:
+		return green
+	case code >= 300 && code < 400:
+		return white
+	case code >= 400 && code < 500:
+		return yellow
+	default:
+		return red
+	}
+}
+
+// MethodColor returns the ANSI colour of the method
+func (p *LogParams) MethodColor() string {
+	switch p.Method {
+	case http.MethodGet:
+		return blue
+	case http.MethodPost:
+		return cyan
+	case http.MethodPut:
+		return yellow
+	case http.MethodDelete:
+		return red
+	case http.MethodPatch:
+		return green
+	case http.MethodHead:
+		return magenta
+	default:
+		return reset
+	}
+}
+
+// ResetColor returns the ANSI code ending a colour
+func (p *LogParams) ResetColor() string {
+	return reset
+}
+
+// IsOutputColor reports whether the output is a terminal that takes colours
+func (p *LogParams) IsOutputColor() bool {
+	return p.isTerm
+}
+
+// LogFormatter turns a finished request into one log line
+type LogFormatter func(params LogParams) string
+
+// DefaultLogFormatter is the coloured text format used by Logger
+var DefaultLogFormatter LogFormatter = func(p LogParams) string {
+	var statusColor, methodColor, resetColor string
+	if p.IsOutputColor() {
+		statusColor, methodColor, resetColor = p.StatusCodeColor(), p.MethodColor(), p.ResetColor()
+	}
+	return fmt.Sprintf("[GEE] %v |%s %3d %s| %13v | %15s | %8d |%s %-7s %s %#v\n",
+		p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, p.StatusCode, resetColor,
+		p.Latency.Truncate(time.Microsecond),
+		p.ClientIP,
+		p.BodySize,
+		methodColor, p.Method, resetColor,
+		p.Path,
+	)
+}
+
+// CommonLogFormatter writes the Apache Common Log Format
+var CommonLogFormatter LogFormatter = func(p LogParams) string {
+	return commonLog(p) + "\n"
+}
+
+// CombinedLogFormatter writes the Apache Combined Log Format, the common
+// format followed by the referer and the user agent
+var CombinedLogFormatter LogFormatter = func(p LogParams) string {
+	return fmt.Sprintf("%s %s %s\n", commonLog(p),
+		strconv.Quote(orDash(p.Request.Referer())), strconv.Quote(orDash(p.Request.UserAgent())))
+}
+
+func commonLog(p LogParams) string {
+	user, _, _ := p.Request.BasicAuth()
+	size := "-"
+	if p.BodySize > 0 {
+		size = strconv.Itoa(p.BodySize)
+	}
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s",
+		orDash(p.ClientIP), orDash(user),
+		p.TimeStamp.Format("02/Jan/2006:15:04:05 -0700"),
+		p.Method, p.Path, p.Request.Proto, p.StatusCode, size)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// JSONLogFormatter writes one JSON object per line
+var JSONLogFormatter LogFormatter = func(p LogParams) string {
+	b, _ := json.Marshal(struct {
+		Time      string  `json:"time"`
+		Status    int     `json:"status"`
+		LatencyMS float64 `json:"latency_ms"`
+		ClientIP  string  `json:"client_ip"`
+		Method    string  `json:"method"`
+		Path      string  `json:"path"`
+		Size      int     `json:"size"`
+		UserAgent string  `json:"user_agent,omitempty"`
+	}{
+		Time:      p.TimeStamp.Format(time.RFC3339Nano),
+		Status:    p.StatusCode,
+		LatencyMS: float64(p.Latency) / float64(time.Millisecond),
+		ClientIP:  p.ClientIP,
+		Method:    p.Method,
+		Path:      p.Path,
+		Size:      p.BodySize,
+		UserAgent: p.Request.UserAgent(),
+	})
+	return string(b) + "\n"
+}
+
+// LoggerConfig configures LoggerWithConfig
+type LoggerConfig struct {
+	// Formatter defaults to DefaultLogFormatter
+	Formatter LogFormatter
+	// Output defaults to DefaultWriter
+	Output io.Writer
+	// SkipPaths are request paths that are not logged, e.g. health checks
+	SkipPaths []string
+}
+
+// Logger logs every request to DefaultWriter. When the engine has a logger
+// set with SetLogger, and neither Formatter nor Output is configured, it
+// emits slog records through Context.Logger instead.
+func Logger() HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{})
+}
+
+// LoggerWithFormatter logs every request with formatter
+func LoggerWithFormatter(formatter LogFormatter) HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{Formatter: formatter})
+}
+
+// LoggerWithWriter logs requests to out, except for skipPaths
+func LoggerWithWriter(out io.Writer, skipPaths ...string) HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{Output: out, SkipPaths: skipPaths})
+}
+
+// LoggerWithConfig returns a Logger middleware configured by conf
+func LoggerWithConfig(conf LoggerConfig) HandlerFunc {
+	structured := conf.Formatter == nil && conf.Output == nil
+	formatter := conf.Formatter
+	if formatter == nil {
+		formatter = DefaultLogFormatter
+	}
+	out := conf.Output
+	if out == nil {
+		out = DefaultWriter
+		if Mode() == TestMode {
+			out = io.Discard
+		}
+	}
+	isTerm := isTerminal(out)
+	skip := make(map[string]bool, len(conf.SkipPaths))
+	for _, path := range conf.SkipPaths {
+		skip[path] = true
+	}
+	return func(c *Context) {
+		start := time.Now()
+		path := c.Req.URL.Path
+		if raw := c.Req.URL.RawQuery; raw != "" {
+			path += "?" + raw
+		}
+		w := &responseWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		if skip[c.Req.URL.Path] {
+			return
+		}
+		now := time.Now()
+		params := LogParams{
+			Request:    c.Req,
+			TimeStamp:  now,
+			StatusCode: w.Status(),
+			Latency:    now.Sub(start),
+			ClientIP:   c.ClientIP(),
+			Method:     c.Req.Method,
+			Path:       path,
+			BodySize:   w.size,
+			Keys:       c.Keys,
+			isTerm:     isTerm,
+		}
+		if structured && c.engine != nil && c.engine.logger != nil {
+			logRecord(c, params)
+			return
+		}
+		fmt.Fprint(out, formatter(params))
+	}
+}
+
+// isTerminal reports whether out is a terminal that takes colours
+func isTerminal(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// responseWriter records the status and the body size of a response
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+// Status returns the status code written, 200 if the handler wrote none
+func (w *responseWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.status == 0 && code >= 200 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Written reports whether the status or a part of the body was sent
+func (w *responseWriter) Written() bool {
+	return w.status != 0
+}
+
+// Hijack hands the connection over, e.g. to a WebSocket, which takes the
+// response out of the hands of gee
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,148 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func logParams() LogParams {
+	req := httptest.NewRequest("GET", "/users?page=2", nil)
+	req.SetBasicAuth("ann", "secret")
+	req.Header.Set("Referer", "https://example.com/")
+	req.Header.Set("User-Agent", `curl/8.0 "quoted"`)
+	return LogParams{
+		Request:    req,
+		TimeStamp:  time.Date(2024, 5, 1, 12, 30, 45, 0, time.FixedZone("", 2*3600)),
+		StatusCode: 404,
+		Latency:    1500 * time.Microsecond,
+		ClientIP:   "192.0.2.1",
+		Method:     "GET",
+		Path:       "/users?page=2",
+		BodySize:   19,
+	}
+}
+
+func TestLogFormatters(t *testing.T) {
+	p := logParams()
+	tests := map[string]struct {
+		formatter LogFormatter
+		want      string
+	}{
+		"default": {DefaultLogFormatter,
+			"[GEE] 2024/05/01 - 12:30:45 | 404 |         1.5ms |       192.0.2.1 |       19 | GET      \"/users?page=2\"\n"},
+		"common": {CommonLogFormatter,
+			`192.0.2.1 - ann [01/May/2024:12:30:45 +0200] "GET /users?page=2 HTTP/1.1" 404 19` + "\n"},
+		"combined": {CombinedLogFormatter,
+			`192.0.2.1 - ann [01/May/2024:12:30:45 +0200] "GET /users?page=2 HTTP/1.1" 404 19 "https://example.com/" "curl/8.0 \"quoted\""` + "\n"},
+	}
+	for name, tt := range tests {
+		if got := tt.formatter(p); got != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", name, got, tt.want)
+		}
+	}
+
+	p.BodySize = 0
+	p.ClientIP = ""
+	p.Request = httptest.NewRequest("GET", "/", nil)
+	want := `- - - [01/May/2024:12:30:45 +0200] "GET /users?page=2 HTTP/1.1" 404 - "-" "-"` + "\n"
+	if got := CombinedLogFormatter(p); got != want {
+		t.Errorf("empty fields:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestJSONLogFormatter(t *testing.T) {
+	line := JSONLogFormatter(logParams())
+	if !strings.HasSuffix(line, "}\n") || strings.Count(line, "\n") != 1 {
+		t.Fatalf("not one line: %q", line)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"time":       "2024-05-01T12:30:45+02:00",
+		"status":     404.0,
+		"latency_ms": 1.5,
+		"client_ip":  "192.0.2.1",
+		"method":     "GET",
+		"path":       "/users?page=2",
+		"size":       19.0,
+		"user_agent": `curl/8.0 "quoted"`,
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %v, want %v", key, got[key], value)
+		}
+	}
+}
+
+func TestLogColors(t *testing.T) {
+	p := LogParams{isTerm: true}
+	for code, color := range map[int]string{101: green, 200: green, 304: white, 404: yellow, 500: red, 0: red} {
+		p.StatusCode = code
+		if p.StatusCodeColor() != color {
+			t.Errorf("status %d has colour %q", code, p.StatusCodeColor())
+		}
+	}
+	p.Method, p.StatusCode = "DELETE", 200
+	if p.MethodColor() != red {
+		t.Errorf("DELETE has colour %q", p.MethodColor())
+	}
+	line := DefaultLogFormatter(p)
+	if !strings.Contains(line, green+" 200 "+reset) || !strings.Contains(line, red+" DELETE  "+reset) {
+		t.Errorf("no colours on a terminal: %q", line)
+	}
+	p.isTerm = false
+	if strings.Contains(DefaultLogFormatter(p), "\033[") {
+		t.Errorf("colours without a terminal")
+	}
+}
+
+func TestLoggerSkipPaths(t *testing.T) {
+	var out bytes.Buffer
+	r := New()
+	r.Use(LoggerWithConfig(LoggerConfig{
+		Formatter: func(p LogParams) string {
+			return p.Method + " " + p.Path + " " + http.StatusText(p.StatusCode) + " " + p.ClientIP + "\n"
+		},
+		Output:    &out,
+		SkipPaths: []string{"/healthz"},
+	}))
+	r.GET("/healthz", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/users", func(c *Context) {
+		c.Status(http.StatusNoContent)
+	})
+	for _, path := range []string{"/healthz", "/healthz?verbose=1", "/users?page=2", "/missing"} {
+		get(r, path, nil)
+	}
+	want := "GET /users?page=2 No Content 192.0.2.1\nGET /missing Not Found 192.0.2.1\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestLoggerWithWriter(t *testing.T) {
+	var out bytes.Buffer
+	r := New()
+	r.Use(LoggerWithWriter(&out, "/skip"))
+	r.GET("/hello", func(c *Context) {
+		c.String(http.StatusOK, "hello")
+	})
+	r.GET("/skip", func(c *Context) {})
+	get(r, "/hello", nil)
+	get(r, "/skip", nil)
+	line := out.String()
+	if strings.Count(line, "\n") != 1 || !strings.Contains(line, "| 200 |") || !strings.Contains(line, `"/hello"`) {
+		t.Errorf("got %q", line)
+	}
+	if !strings.Contains(line, "|        5 |") {
+		t.Errorf("body size missing: %q", line)
+	}
+}
@@ -0,0 +1,78 @@
+package gee
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// EnvGeeMode is the environment variable selecting how gee runs
+const EnvGeeMode = "GEE_MODE"
+
+// Modes of gee, see SetMode
+const (
+	// DebugMode prints the route table and warnings, reloads templates on
+	// every render and logs panics with source lines
+	DebugMode = "debug"
+	// ReleaseMode is silent and caches templates
+	ReleaseMode = "release"
+	// TestMode is silent and discards the default output of Logger and
+	// Recovery, for test suites
+	TestMode = "test"
+)
+
+var geeMode atomic.Value
+
+func init() {
+	SetMode(os.Getenv(EnvGeeMode))
+}
+
+// SetMode sets the mode of gee, "" selects DebugMode. It panics on an
+// unknown mode.
+func SetMode(value string) {
+	if value == "" {
+		value = DebugMode
+	}
+	switch value {
+	case DebugMode, ReleaseMode, TestMode:
+		geeMode.Store(value)
+	default:
+		panic("gee: unknown mode " + value + ", use debug, release or test")
+	}
+}
+
+// Mode returns the current mode of gee
+func Mode() string {
+	return geeMode.Load().(string)
+}
+
+// IsDebugging reports whether gee runs in debug mode, which is the default
+func IsDebugging() bool {
+	return Mode() == DebugMode
+}
+
+// debugPrint writes a line to DefaultWriter in debug mode only
+func debugPrint(format string, values ...interface{}) {
+	if !IsDebugging() {
+		return
+	}
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+	fmt.Fprintf(DefaultWriter, "[GEE-debug] "+format, values...)
+}
+
+func debugPrintWARNING() {
+	debugPrint(`[WARNING] Running in "debug" mode. Switch to "release" mode in production.
+ - using env:	export GEE_MODE=release
+ - using code:	gee.SetMode(gee.ReleaseMode)
+`)
+}
+
+// nameOfFunction returns the name of f for the route table
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
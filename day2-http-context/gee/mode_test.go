@@ -0,0 +1,106 @@
+package gee
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// initialMode is the mode selected by GEE_MODE, before the tests switch
+// to TestMode
+var initialMode string
+
+func init() {
+	initialMode = Mode()
+	SetMode(TestMode)
+}
+
+func TestSetMode(t *testing.T) {
+	defer SetMode(Mode())
+	tests := map[string]string{
+		"":          DebugMode,
+		DebugMode:   DebugMode,
+		ReleaseMode: ReleaseMode,
+		TestMode:    TestMode,
+	}
+	for value, want := range tests {
+		SetMode(value)
+		if Mode() != want || IsDebugging() != (want == DebugMode) {
+			t.Errorf("SetMode(%q): Mode = %q, IsDebugging = %v", value, Mode(), IsDebugging())
+		}
+	}
+
+	SetMode(ReleaseMode)
+	for _, value := range []string{"Release", "prod", " debug"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("SetMode(%q) did not panic", value)
+				}
+			}()
+			SetMode(value)
+		}()
+		if Mode() != ReleaseMode {
+			t.Errorf("SetMode(%q) changed the mode to %q", value, Mode())
+		}
+	}
+}
+
+func TestDebugPrint(t *testing.T) {
+	defer SetMode(Mode())
+	defer func(w io.Writer) { DefaultWriter = w }(DefaultWriter)
+	var out bytes.Buffer
+	DefaultWriter = &out
+
+	for _, mode := range []string{ReleaseMode, TestMode} {
+		SetMode(mode)
+		New().GET("/", func(c *Context) {})
+		if out.Len() != 0 {
+			t.Errorf("%s mode printed %q", mode, out.String())
+		}
+	}
+
+	SetMode(DebugMode)
+	New().GET("/users/:id", func(c *Context) {})
+	if !strings.Contains(out.String(), `[GEE-debug] [WARNING] Running in "debug" mode`) {
+		t.Errorf("no warning in debug mode: %q", out.String())
+	}
+	if !strings.Contains(out.String(), "[GEE-debug] GET    /users/:id") {
+		t.Errorf("no route table in debug mode: %q", out.String())
+	}
+}
+
+// TestModeFromEnv runs the test binary again with GEE_MODE set, as the
+// variable is only read when the package is initialised
+func TestModeFromEnv(t *testing.T) {
+	if want := os.Getenv("GEE_MODE_WANT"); want != "" {
+		// the child, before the init of the tests forces TestMode
+		if initialMode != want {
+			t.Fatalf("GEE_MODE=%q started in %q mode, want %q", os.Getenv(EnvGeeMode), initialMode, want)
+		}
+		return
+	}
+	tests := map[string]string{
+		"":          DebugMode,
+		DebugMode:   DebugMode,
+		ReleaseMode: ReleaseMode,
+		TestMode:    TestMode,
+	}
+	for value, want := range tests {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestModeFromEnv$")
+		cmd.Env = append(os.Environ(), EnvGeeMode+"="+value, "GEE_MODE_WANT="+want)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("GEE_MODE=%q: %v\n%s", value, err, out)
+		}
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), EnvGeeMode+"=production")
+	out, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(out), "unknown mode production") {
+		t.Errorf("an unknown GEE_MODE was accepted: %v\n%s", err, out)
+	}
+}
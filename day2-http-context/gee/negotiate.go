@@ -0,0 +1,230 @@
+package gee
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MIME types understood by Negotiate
+const (
+	MIMEJSON  = "application/json"
+	MIMEHTML  = "text/html"
+	MIMEXML   = "application/xml"
+	MIMEXML2  = "text/xml"
+	MIMEPlain = "text/plain"
+	MIMEYAML  = "application/yaml"
+)
+
+// Negotiate holds one payload per format, Context.Negotiate picks the
+// one the client prefers. Offered defaults to the formats that have data.
+type Negotiate struct {
+	Offered  []string
+	HTMLName string
+	HTMLData interface{}
+	JSONData interface{}
+	XMLData  interface{}
+	YAMLData interface{}
+	Data     interface{}
+}
+
+// qualityItem is one entry of a comma separated header such as Accept-Encoding
+type qualityItem struct {
+	value string
+	q     float64
+}
+
+// parseQualityList parses a header of q-valued items, highest q first
+func parseQualityList(header string) []qualityItem {
+	var items []qualityItem
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		value := strings.ToLower(strings.TrimSpace(params[0]))
+		if value == "" {
+			continue
+		}
+		item := qualityItem{value: value, q: 1}
+		for _, param := range params[1:] {
+			key, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.ToLower(strings.TrimSpace(key)) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+			item.q = q
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+	return items
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header allows coding
+func acceptsEncoding(header string, coding string) bool {
+	wildcard := 0.0
+	for _, item := range parseQualityList(header) {
+		switch item.value {
+		case coding:
+			return item.q > 0
+		case "*":
+			wildcard = item.q
+		}
+	}
+	return wildcard > 0
+}
+
+// NegotiateEncoding returns the content coding of offered that the
+// Accept-Encoding header prefers, earlier offers winning ties, or "" when
+// none is acceptable or the header is absent
+func (c *Context) NegotiateEncoding(offered ...string) string {
+	header := c.Req.Header.Get("Accept-Encoding")
+	if header == "" {
+		return ""
+	}
+	items := parseQualityList(header)
+	best, bestQ := "", 0.0
+	for _, coding := range offered {
+		q, wildcard, explicit := 0.0, 0.0, false
+		for _, item := range items {
+			if item.value == strings.ToLower(coding) {
+				q, explicit = item.q, true
+				break
+			}
+			if item.value == "*" && wildcard == 0 {
+				wildcard = item.q
+			}
+		}
+		if !explicit {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// acceptSpec is one media range of an Accept header
+type acceptSpec struct {
+	typ, subtype string
+	q            float64
+}
+
+// parseAccept parses an Accept header into media ranges ordered by q-value
+func parseAccept(header string) []acceptSpec {
+	var specs []acceptSpec
+	for _, item := range parseQualityList(header) {
+		mediaRange := item.value
+		if mediaRange == "*" {
+			mediaRange = "*/*"
+		}
+		typ, subtype, ok := strings.Cut(mediaRange, "/")
+		if !ok || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+			continue
+		}
+		specs = append(specs, acceptSpec{typ: typ, subtype: subtype, q: item.q})
+	}
+	return specs
+}
+
+// specificity ranks how precisely spec matches, -1 means it does not match
+func (spec acceptSpec) specificity(typ, subtype string) int {
+	switch {
+	case spec.typ == typ && spec.subtype == subtype:
+		return 2
+	case spec.typ == typ && spec.subtype == "*":
+		return 1
+	case spec.typ == "*":
+		return 0
+	}
+	return -1
+}
+
+// quality returns the q-value of the most specific range matching mime
+func quality(specs []acceptSpec, mime string) float64 {
+	typ, subtype, _ := strings.Cut(strings.ToLower(mime), "/")
+	best, q := -1, 0.0
+	for _, spec := range specs {
+		if s := spec.specificity(typ, subtype); s > best {
+			best, q = s, spec.q
+		}
+	}
+	return q
+}
+
+// NegotiateFormat returns the offered type the client accepts best, ties go
+// to the earlier offer. It returns "" when none of them is acceptable.
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		panic("gee: you must provide at least one offer")
+	}
+	header := c.Req.Header.Get("Accept")
+	if strings.TrimSpace(header) == "" {
+		return offered[0]
+	}
+	specs := parseAccept(header)
+	format, bestQ := "", 0.0
+	for _, offer := range offered {
+		if q := quality(specs, offer); q > bestQ {
+			format, bestQ = offer, q
+		}
+	}
+	return format
+}
+
+// Negotiate renders the payload of config that best matches the Accept
+// header, or responds 406 Not Acceptable
+func (c *Context) Negotiate(code int, config Negotiate) {
+	c.Writer.Header().Add("Vary", "Accept")
+	offered := config.Offered
+	if len(offered) == 0 {
+		offered = config.offers()
+	}
+	if len(offered) == 0 {
+		c.String(http.StatusNotAcceptable, "406 NOT ACCEPTABLE\n")
+		return
+	}
+	switch format := c.NegotiateFormat(offered...); format {
+	case MIMEJSON:
+		c.JSON(code, config.pick(config.JSONData))
+	case MIMEXML, MIMEXML2:
+		c.XML(code, config.pick(config.XMLData))
+	case MIMEYAML:
+		c.YAML(code, config.pick(config.YAMLData))
+	case MIMEHTML:
+		c.HTML(code, config.HTMLName, config.pick(config.HTMLData))
+	case "":
+		c.String(http.StatusNotAcceptable, "406 NOT ACCEPTABLE\n")
+	default:
+		c.String(http.StatusNotAcceptable, "406 NOT ACCEPTABLE: %s is not supported\n", format)
+	}
+}
+
+// offers lists the formats config carries data for, in preference order
+func (config Negotiate) offers() []string {
+	var offered []string
+	if config.JSONData != nil || config.Data != nil {
+		offered = append(offered, MIMEJSON)
+	}
+	if config.XMLData != nil || config.Data != nil {
+		offered = append(offered, MIMEXML)
+	}
+	if config.YAMLData != nil || config.Data != nil {
+		offered = append(offered, MIMEYAML)
+	}
+	if config.HTMLName != "" {
+		offered = append(offered, MIMEHTML)
+	}
+	return offered
+}
+
+// pick falls back to the shared Data when a format has no payload of its own
+func (config Negotiate) pick(data interface{}) interface{} {
+	if data != nil {
+		return data
+	}
+	return config.Data
+}
@@ -0,0 +1,114 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	offered := []string{MIMEJSON, MIMEXML, MIMEHTML}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", MIMEJSON},
+		{"application/xml", MIMEXML},
+		{"text/html, application/json;q=0.9", MIMEHTML},
+		{"text/html;q=0.5, application/xml;q=0.8", MIMEXML},
+		{"*/*", MIMEJSON},
+		{"*", MIMEJSON},
+		{"text/*", MIMEHTML},
+		{"text/*;q=0.3, application/*;q=0.2", MIMEHTML},
+		{"application/*, application/json;q=0", MIMEXML},
+		{"*/*;q=0.1, application/xml", MIMEXML},
+		{"APPLICATION/XML", MIMEXML},
+		{"application/json;q=0", ""},
+		{"image/png", ""},
+		{"text/html;q=abc", ""},
+		{"text/html;q=2", ""},
+		{"*/html, image/png", ""},
+		{"application/xml;q=0.5, application/json;q=0.5", MIMEJSON},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", tt.accept)
+		c := newContext(httptest.NewRecorder(), req)
+		if got := c.NegotiateFormat(offered...); got != tt.want {
+			t.Errorf("Accept %q: got %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"br;q=1, gzip;q=0.5", "br"},
+		{"gzip, br", "gzip"},
+		{"*", "gzip"},
+		{"*, gzip;q=0", "br"},
+		{"deflate", ""},
+		{"GZIP", "gzip"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", tt.accept)
+		c := newContext(httptest.NewRecorder(), req)
+		if got := c.NegotiateEncoding("gzip", "br"); got != tt.want {
+			t.Errorf("Accept-Encoding %q: got %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	r := New()
+	r.LoadHTMLFS(fstest.MapFS{"user.tmpl": {Data: []byte(`<p>{{.}}</p>`)}}, "*.tmpl")
+	r.GET("/user", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{
+			HTMLName: "user.tmpl",
+			HTMLData: "ann",
+			Data:     H{"name": "ann"},
+		})
+	})
+	r.GET("/json", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{Offered: []string{MIMEJSON}, Data: H{"name": "ann"}})
+	})
+	r.GET("/nothing", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{})
+	})
+	tests := []struct {
+		path, accept string
+		code         int
+		contentType  string
+		body         string
+	}{
+		{"/user", "", 200, "application/json", `{"name":"ann"}`},
+		{"/user", "application/xml", 200, "application/xml", "<name>ann</name>"},
+		{"/user", "application/yaml", 200, "application/yaml", "name: ann\n"},
+		{"/user", "text/html,application/xhtml+xml,*/*;q=0.8", 200, "text/html", "<p>ann</p>"},
+		{"/user", "image/png", 406, "text/plain", "406 NOT ACCEPTABLE\n"},
+		{"/json", "application/xml", 406, "text/plain", "406 NOT ACCEPTABLE\n"},
+		{"/nothing", "", 406, "text/plain", "406 NOT ACCEPTABLE\n"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s %q: got %d %q, want %d %q", tt.path, tt.accept, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+			t.Errorf("%s %q: Content-Type = %q, want %s", tt.path, tt.accept, ct, tt.contentType)
+		}
+		if vary := w.Header().Values("Vary"); len(vary) != 1 || vary[0] != "Accept" {
+			t.Errorf("%s %q: Vary = %v, want Accept", tt.path, tt.accept, vary)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package gee
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// MIMEProblemJSON is the media type of Problem, see RFC 9457
+const MIMEProblemJSON = "application/problem+json"
+
+// Problem is an RFC 9457 problem details object. Extensions are written as
+// additional members next to the standard ones.
+type Problem struct {
+	// Type is a URI identifying the problem type, "about:blank" if empty
+	Type string
+	// Title summarises the problem type, the status text by default
+	Title    string
+	Status   int
+	Detail   string
+	Instance string
+	// Extensions may not override the standard members
+	Extensions map[string]interface{}
+}
+
+// MarshalJSON flattens the extensions into the object
+func (p Problem) MarshalJSON() ([]byte, error) {
+	obj := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		obj[k] = v
+	}
+	obj["type"] = p.Type
+	if p.Type == "" {
+		obj["type"] = "about:blank"
+	}
+	obj["title"] = p.Title
+	if p.Title == "" {
+		obj["title"] = http.StatusText(p.Status)
+	}
+	if p.Status != 0 {
+		obj["status"] = p.Status
+	}
+	if p.Detail != "" {
+		obj["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		obj["instance"] = p.Instance
+	}
+	return json.Marshal(obj)
+}
+
+func (p Problem) Error() string {
+	title := p.Title
+	if title == "" {
+		title = http.StatusText(p.Status)
+	}
+	if p.Detail == "" {
+		return title
+	}
+	return title + ": " + p.Detail
+}
+
+// Problem answers with p as application/problem+json, Status defaults to 500
+func (c *Context) Problem(p Problem) {
+	if p.Status == 0 {
+		p.Status = http.StatusInternalServerError
+	}
+	c.SetHeader("Content-Type", MIMEProblemJSON)
+	c.JSON(p.Status, p)
+}
+
+// AbortWithStatus stops the chain and answers with code the way the router
+// answers 404 and 405: a Problem carrying detail when the engine has
+// ProblemDetails set, a "code STATUS TEXT: detail" line otherwise. Unlike
+// Context.Error it does not go through the ErrorHandler, so middlewares
+// such as rate limiters can reject a request whatever handler is set.
+func (c *Context) AbortWithStatus(code int, detail string) {
+	c.Abort()
+	c.statusResponse(code, detail)
+}
+
+// statusResponse writes the default response for code, a Problem when the
+// engine is configured with ProblemDetails and a text line otherwise
+func (c *Context) statusResponse(code int, detail string) {
+	if c.engine != nil && c.engine.ProblemDetails {
+		c.Problem(Problem{Status: code, Detail: detail, Instance: c.Req.URL.Path})
+		return
+	}
+	c.String(code, "%d %s: %s\n", code, strings.ToUpper(http.StatusText(code)), detail)
+}
@@ -0,0 +1,221 @@
+package gee
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAbortWithStatus(t *testing.T) {
+	for _, problem := range []bool{false, true} {
+		r := New()
+		r.ProblemDetails = problem
+		// AbortWithStatus must not depend on the ErrorHandler
+		r.ErrorHandler = func(c *Context) {}
+		reached := false
+		r.Use(func(c *Context) {
+			c.AbortWithStatus(http.StatusTooManyRequests, "slow down")
+		})
+		r.GET("/", func(c *Context) { reached = true })
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if reached {
+			t.Errorf("problem=%v: the handler ran after AbortWithStatus", problem)
+		}
+		if w.Code != http.StatusTooManyRequests {
+			t.Errorf("problem=%v: got %d, want 429", problem, w.Code)
+		}
+		if !problem {
+			if got := w.Body.String(); got != "429 TOO MANY REQUESTS: slow down\n" {
+				t.Errorf("got body %q", got)
+			}
+			continue
+		}
+		var body map[string]interface{}
+		if w.Header().Get("Content-Type") != MIMEProblemJSON || json.Unmarshal(w.Body.Bytes(), &body) != nil {
+			t.Fatalf("got %q %q, want a Problem", w.Header().Get("Content-Type"), w.Body.String())
+		}
+		if body["status"] != 429.0 || body["detail"] != "slow down" || body["instance"] != "/" {
+			t.Errorf("got Problem %v", body)
+		}
+	}
+}
+
+func TestProblemJSON(t *testing.T) {
+	tests := []struct {
+		problem Problem
+		want    string
+	}{
+		{Problem{Status: 404}, `{"status":404,"title":"Not Found","type":"about:blank"}`},
+		{Problem{}, `{"title":"","type":"about:blank"}`},
+		{
+			Problem{Type: "https://example.com/out-of-credit", Title: "Out of credit", Status: 403,
+				Detail: "balance is 30", Instance: "/accounts/1", Extensions: map[string]interface{}{"balance": 30}},
+			`{"balance":30,"detail":"balance is 30","instance":"/accounts/1","status":403,"title":"Out of credit","type":"https://example.com/out-of-credit"}`,
+		},
+		// extensions can not override the standard members
+		{
+			Problem{Status: 400, Extensions: map[string]interface{}{"status": 200, "type": "x", "title": "y"}},
+			`{"status":400,"title":"Bad Request","type":"about:blank"}`,
+		},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.problem)
+		if err != nil || string(b) != tt.want {
+			t.Errorf("got %s %v, want %s", b, err, tt.want)
+		}
+	}
+	if got := (Problem{Status: 409, Detail: "taken"}).Error(); got != "Conflict: taken" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestProblemResponses(t *testing.T) {
+	r := New()
+	r.ProblemDetails = true
+	r.GET("/problem", func(c *Context) {
+		c.Problem(Problem{Detail: "boom"})
+	})
+	r.GET("/returned", func(c *Context) {
+		c.Error(Problem{Status: http.StatusConflict, Detail: "name taken", Extensions: map[string]interface{}{"name": "ann"}})
+	})
+	r.GET("/private", func(c *Context) {
+		c.Error(errors.New("db password is hunter2"))
+	})
+	r.GET("/public", func(c *Context) {
+		c.Error(errors.New("quota exceeded")).SetType(ErrorTypePublic).SetStatus(http.StatusForbidden).SetMeta(H{"quota": 10})
+	})
+	tests := []struct {
+		path string
+		code int
+		want map[string]interface{}
+	}{
+		{"/problem", 500, map[string]interface{}{"status": 500.0, "title": "Internal Server Error", "detail": "boom"}},
+		{"/returned", 409, map[string]interface{}{"status": 409.0, "detail": "name taken", "name": "ann"}},
+		{"/private", 500, map[string]interface{}{"status": 500.0, "instance": "/private", "detail": nil}},
+		{"/public", 403, map[string]interface{}{"status": 403.0, "detail": "quota exceeded", "quota": 10.0}},
+	}
+	for _, tt := range tests {
+		w := get(r, tt.path, nil)
+		var body map[string]interface{}
+		if w.Header().Get("Content-Type") != MIMEProblemJSON || json.Unmarshal(w.Body.Bytes(), &body) != nil {
+			t.Errorf("%s: got %q %q, want a Problem", tt.path, w.Header().Get("Content-Type"), w.Body.String())
+			continue
+		}
+		if w.Code != tt.code {
+			t.Errorf("%s: got %d, want %d", tt.path, w.Code, tt.code)
+		}
+		for key, value := range tt.want {
+			if body[key] != value {
+				t.Errorf("%s: %s = %v, want %v", tt.path, key, body[key], value)
+			}
+		}
+	}
+}
+
+type signup struct {
+	Name string `json:"name"`
+}
+
+func (s signup) Validate() error {
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func TestBindJSON(t *testing.T) {
+	for _, problem := range []bool{false, true} {
+		r := New()
+		r.ProblemDetails = problem
+		r.MaxBodyBytes = 32
+		r.POST("/signup", func(c *Context) {
+			var s signup
+			if c.BindJSON(&s) != nil {
+				return
+			}
+			c.String(http.StatusCreated, "hello %s", s.Name)
+		})
+		tests := []struct {
+			contentType, body string
+			code              int
+			detail            string
+		}{
+			{"application/json", `{"name":"ann"}`, 201, ""},
+			{"application/merge-patch+json; charset=utf-8", `{"name":"ann"}`, 201, ""},
+			{"text/plain", `{"name":"ann"}`, 415, `content type "text/plain" is not JSON`},
+			{"", `{"name":"ann"}`, 415, `content type "" is not JSON`},
+			{"application/json", `{"name":"` + strings.Repeat("a", 40) + `"}`, 413, "request body exceeds 32 bytes"},
+			{"application/json", ``, 400, "request body is empty"},
+			{"application/json", `{"name":`, 400, "unexpected EOF"},
+			{"application/json", `{"name":""}`, 422, "name is required"},
+		}
+		for _, tt := range tests {
+			req := httptest.NewRequest("POST", "/signup", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.code {
+				t.Errorf("problem=%v %q %q: got %d %q, want %d", problem, tt.contentType, tt.body, w.Code, w.Body.String(), tt.code)
+				continue
+			}
+			if tt.code == 201 {
+				continue
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			key := "error"
+			if problem {
+				key = "detail"
+			}
+			if body[key] != tt.detail {
+				t.Errorf("problem=%v %q %q: %s = %v, want %q", problem, tt.contentType, tt.body, key, body[key], tt.detail)
+			}
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	for _, handle := range []bool{false, true} {
+		for _, problem := range []bool{false, true} {
+			r := New()
+			r.HandleMethodNotAllowed = handle
+			r.ProblemDetails = problem
+			r.GET("/users/:id", func(c *Context) {})
+			r.POST("/users/:id", func(c *Context) {})
+			r.HandleE("DELETE", "/users/:id", func(c *Context) error { return nil })
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("PUT", "/users/1", nil))
+			if !handle {
+				if w.Code != http.StatusNotFound || w.Header().Get("Allow") != "" {
+					t.Errorf("disabled: got %d with Allow %q, want 404", w.Code, w.Header().Get("Allow"))
+				}
+				continue
+			}
+			if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "DELETE, GET, POST" {
+				t.Errorf("problem=%v: got %d with Allow %q", problem, w.Code, w.Header().Get("Allow"))
+			}
+			if problem && w.Header().Get("Content-Type") != MIMEProblemJSON {
+				t.Errorf("problem=%v: Content-Type = %q", problem, w.Header().Get("Content-Type"))
+			}
+			if !problem && w.Body.String() != "405 METHOD NOT ALLOWED: PUT /users/1\n" {
+				t.Errorf("got body %q", w.Body.String())
+			}
+
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("PUT", "/other", nil))
+			if w.Code != http.StatusNotFound || w.Header().Get("Allow") != "" {
+				t.Errorf("problem=%v: unknown path got %d with Allow %q, want 404", problem, w.Code, w.Header().Get("Allow"))
+			}
+		}
+	}
+}
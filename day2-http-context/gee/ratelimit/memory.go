@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"hash/maphash"
+	"math"
+	"sync"
+	"time"
+)
+
+// MemoryOptions configure NewMemoryStore
+type MemoryOptions struct {
+	// Shards is the number of independently locked maps, 64 by default
+	Shards int
+	// MaxKeys bounds the keys held, 0 means no bound. Expired keys go
+	// first, then arbitrary ones.
+	MaxKeys int
+}
+
+// MemoryStore keeps the state of keys in memory, split in shards so that
+// requests for different keys rarely contend. Keys that are back to a full
+// quota are swept now and then.
+type MemoryStore struct {
+	seed    maphash.Seed
+	shards  []*shard
+	maxKeys int
+}
+
+type shard struct {
+	mu        sync.Mutex
+	items     map[string]*item
+	lastSweep time.Time
+}
+
+// item is the state of a key: the bucket of TokenBucket or the hit log of
+// SlidingWindow
+type item struct {
+	tokens  float64
+	last    time.Time
+	hits    []time.Time
+	expires time.Time
+}
+
+// NewMemoryStore is the constructor of ratelimit.MemoryStore
+func NewMemoryStore(options MemoryOptions) *MemoryStore {
+	if options.Shards <= 0 {
+		options.Shards = 64
+	}
+	s := &MemoryStore{seed: maphash.MakeSeed(), shards: make([]*shard, options.Shards)}
+	if options.MaxKeys > 0 {
+		s.maxKeys = (options.MaxKeys + options.Shards - 1) / options.Shards
+	}
+	now := time.Now()
+	for i := range s.shards {
+		s.shards[i] = &shard{items: make(map[string]*item), lastSweep: now}
+	}
+	return s
+}
+
+// Take implements Store
+func (s *MemoryStore) Take(_ context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	sh := s.shards[maphash.String(s.seed, key)%uint64(len(s.shards))]
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.sweep(now)
+	it, ok := sh.items[key]
+	if !ok || now.After(it.expires) {
+		if !ok {
+			sh.evict(now, s.maxKeys)
+		}
+		it = &item{tokens: float64(policy.capacity()), last: now}
+		sh.items[key] = it
+	}
+	var result Result
+	if policy.Algorithm == TokenBucket {
+		result = it.takeToken(policy, now)
+	} else {
+		result = it.takeWindow(policy, now)
+	}
+	it.expires = now.Add(result.Reset)
+	return result, nil
+}
+
+// takeToken refills the bucket for the time elapsed, then takes a token
+func (it *item) takeToken(policy Policy, now time.Time) Result {
+	capacity := float64(policy.capacity())
+	rate := float64(policy.Limit) / float64(policy.Window) // tokens per ns
+	if elapsed := now.Sub(it.last); elapsed > 0 {
+		it.tokens = math.Min(capacity, it.tokens+float64(elapsed)*rate)
+		it.last = now
+	}
+	result := Result{Limit: policy.capacity()}
+	if it.tokens >= 1 {
+		it.tokens--
+		result.Allowed = true
+	} else {
+		// rounded up, a retry exactly after RetryAfter must find the token
+		result.RetryAfter = time.Duration(math.Ceil((1 - it.tokens) / rate))
+	}
+	result.Remaining = int(it.tokens)
+	result.Reset = time.Duration((capacity - it.tokens) / rate)
+	return result
+}
+
+// takeWindow drops the hits older than the window, then logs the hit
+func (it *item) takeWindow(policy Policy, now time.Time) Result {
+	start := now.Add(-policy.Window)
+	i := 0
+	for i < len(it.hits) && !it.hits[i].After(start) {
+		i++
+	}
+	it.hits = it.hits[i:]
+	result := Result{Limit: policy.Limit}
+	if len(it.hits) < policy.Limit {
+		it.hits = append(it.hits, now)
+		result.Allowed = true
+	} else {
+		result.RetryAfter = it.hits[0].Add(policy.Window).Sub(now)
+	}
+	result.Remaining = policy.Limit - len(it.hits)
+	result.Reset = it.hits[len(it.hits)-1].Add(policy.Window).Sub(now)
+	return result
+}
+
+// sweep drops the expired keys once a minute
+func (sh *shard) sweep(now time.Time) {
+	if now.Sub(sh.lastSweep) < time.Minute {
+		return
+	}
+	for key, it := range sh.items {
+		if now.After(it.expires) {
+			delete(sh.items, key)
+		}
+	}
+	sh.lastSweep = now
+}
+
+// evict makes room for a new key in a full shard, expired keys first
+func (sh *shard) evict(now time.Time, maxKeys int) {
+	if maxKeys <= 0 || len(sh.items) < maxKeys {
+		return
+	}
+	sh.lastSweep = time.Time{}
+	sh.sweep(now)
+	for key := range sh.items {
+		if len(sh.items) < maxKeys {
+			break
+		}
+		delete(sh.items, key)
+	}
+}
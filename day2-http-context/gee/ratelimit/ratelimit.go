@@ -0,0 +1,165 @@
+// Package ratelimit limits how often a client may call the routes it
+// guards.
+//
+//	r.Use(ratelimit.New(ratelimit.Config{
+//		Policy: ratelimit.Policy{Algorithm: ratelimit.SlidingWindow, Limit: 5, Window: time.Minute},
+//		Match:  func(c *gee.Context) bool { return c.FullPath() == "/login" },
+//	}))
+//
+// Every response carries the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers, a denied request is answered with 429 and
+// Retry-After, as a Problem when the engine has ProblemDetails set.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gee"
+)
+
+// Algorithm selects how hits are counted
+type Algorithm int
+
+const (
+	// TokenBucket refills Limit tokens per Window into a bucket of Burst
+	// tokens, allowing short bursts at a steady average rate
+	TokenBucket Algorithm = iota
+	// SlidingWindow allows Limit hits in any Window, remembering the time
+	// of every hit
+	SlidingWindow
+)
+
+// Policy is the limit applied to every key
+type Policy struct {
+	Algorithm Algorithm
+	Limit     int
+	Window    time.Duration
+	// Burst is the bucket size of TokenBucket, Limit by default
+	Burst int
+}
+
+func (p Policy) validate() error {
+	if p.Limit <= 0 || p.Window <= 0 {
+		return errors.New("ratelimit: Limit and Window must be positive")
+	}
+	if p.Algorithm != TokenBucket && p.Algorithm != SlidingWindow {
+		return fmt.Errorf("ratelimit: unknown algorithm %d", p.Algorithm)
+	}
+	return nil
+}
+
+// capacity is the most hits that may be allowed at once
+func (p Policy) capacity() int {
+	if p.Algorithm == TokenBucket && p.Burst > 0 {
+		return p.Burst
+	}
+	return p.Limit
+}
+
+// Result is the outcome of a hit
+type Result struct {
+	Allowed bool
+	// Limit is the number of hits allowed at once
+	Limit     int
+	Remaining int
+	// Reset is the time until the quota is fully available again
+	Reset time.Duration
+	// RetryAfter is the time until the next hit is allowed, when denied
+	RetryAfter time.Duration
+}
+
+// Store keeps the state of every key. Take records a hit for key under
+// policy and must be atomic per key, so a shared backend can implement it
+// with a server-side script.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error)
+}
+
+// KeyFunc returns the key a request is counted under
+type KeyFunc func(c *gee.Context) string
+
+// KeyByClientIP counts requests per client IP, see gee.Context.ClientIP
+func KeyByClientIP(c *gee.Context) string {
+	return c.ClientIP()
+}
+
+// KeyByRoute counts requests per route, across clients
+func KeyByRoute(c *gee.Context) string {
+	return c.Method + " " + c.FullPath()
+}
+
+// KeyByContext counts requests per value stored under key in the Context,
+// such as the user set by an authentication middleware, or per client IP
+// when it is missing
+func KeyByContext(key string) KeyFunc {
+	return func(c *gee.Context) string {
+		if value, ok := c.Get(key); ok {
+			return fmt.Sprintf("%s=%v", key, value)
+		}
+		return c.ClientIP()
+	}
+}
+
+// Config configures New
+type Config struct {
+	Policy Policy
+	// Store defaults to a new MemoryStore
+	Store Store
+	// KeyFunc defaults to KeyByClientIP
+	KeyFunc KeyFunc
+	// Name prefixes the keys, so that limiters can share a Store
+	Name string
+	// Match selects the requests that are limited, all by default
+	Match func(c *gee.Context) bool
+}
+
+// New returns the rate limiting middleware, it panics on an invalid
+// policy. When the store fails the request is let through and the error
+// added to c.Errors.
+func New(config Config) gee.HandlerFunc {
+	if err := config.Policy.validate(); err != nil {
+		panic(err)
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryStore(MemoryOptions{})
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = KeyByClientIP
+	}
+	policy := config.Policy
+	return func(c *gee.Context) {
+		if config.Match != nil && !config.Match(c) {
+			c.Next()
+			return
+		}
+		key := config.Name + ":" + config.KeyFunc(c)
+		result, err := config.Store.Take(c.Req.Context(), key, policy, time.Now())
+		if err != nil {
+			c.Error(err)
+			c.Next()
+			return
+		}
+		header := c.Writer.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", seconds(result.Reset))
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", policy.Limit, seconds(policy.Window)))
+		if !result.Allowed {
+			header.Set("Retry-After", seconds(result.RetryAfter))
+			c.AbortWithStatus(http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		c.Next()
+	}
+}
+
+// seconds rounds d up to whole seconds
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
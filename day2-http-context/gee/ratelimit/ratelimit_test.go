@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gee"
+)
+
+func init() {
+	gee.SetMode(gee.TestMode)
+}
+
+// take records a hit at offset from a fixed start and checks the outcome
+func take(t *testing.T, store Store, policy Policy, offset time.Duration, allowed bool, remaining int) Result {
+	t.Helper()
+	start := time.Unix(1700000000, 0)
+	result, err := store.Take(context.Background(), "k", policy, start.Add(offset))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed != allowed || result.Remaining != remaining {
+		t.Fatalf("at %v: got allowed %v remaining %d, want %v %d",
+			offset, result.Allowed, result.Remaining, allowed, remaining)
+	}
+	return result
+}
+
+func TestTokenBucket(t *testing.T) {
+	store := NewMemoryStore(MemoryOptions{})
+	policy := Policy{Algorithm: TokenBucket, Limit: 2, Window: time.Second, Burst: 3}
+	take(t, store, policy, 0, true, 2)
+	take(t, store, policy, 0, true, 1)
+	take(t, store, policy, 0, true, 0)
+	denied := take(t, store, policy, 0, false, 0)
+	if denied.RetryAfter != 500*time.Millisecond {
+		t.Errorf("RetryAfter = %v, want one token at 2/s", denied.RetryAfter)
+	}
+	// half a second refills one token
+	take(t, store, policy, 500*time.Millisecond, true, 0)
+	take(t, store, policy, 500*time.Millisecond, false, 0)
+	// the bucket never holds more than Burst
+	take(t, store, policy, time.Hour, true, 2)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	store := NewMemoryStore(MemoryOptions{})
+	policy := Policy{Algorithm: SlidingWindow, Limit: 2, Window: time.Minute}
+	take(t, store, policy, 0, true, 1)
+	take(t, store, policy, 30*time.Second, true, 0)
+	denied := take(t, store, policy, 40*time.Second, false, 0)
+	if denied.RetryAfter != 20*time.Second {
+		t.Errorf("RetryAfter = %v, want the first hit to leave the window", denied.RetryAfter)
+	}
+	// the first hit left the window, the second is still in it
+	take(t, store, policy, 61*time.Second, true, 0)
+	take(t, store, policy, 62*time.Second, false, 0)
+	take(t, store, policy, 91*time.Second, true, 0)
+}
+
+func newLimitedEngine(problem bool) *gee.Engine {
+	r := gee.New()
+	r.ProblemDetails = problem
+	r.Use(New(Config{Policy: Policy{Algorithm: SlidingWindow, Limit: 2, Window: time.Minute}}))
+	r.GET("/", func(c *gee.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	return r
+}
+
+func TestHeaders(t *testing.T) {
+	r := newLimitedEngine(false)
+	var w *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if i == 0 {
+			want := map[string]string{
+				"RateLimit-Limit":     "2",
+				"RateLimit-Remaining": "1",
+				"RateLimit-Reset":     "60",
+				"RateLimit-Policy":    "2;w=60",
+			}
+			for name, value := range want {
+				if got := w.Header().Get(name); got != value {
+					t.Errorf("%s = %q, want %q", name, got, value)
+				}
+			}
+			if w.Header().Get("Retry-After") != "" {
+				t.Errorf("Retry-After set on an allowed request")
+			}
+		}
+	}
+	if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), "rate limit exceeded") {
+		t.Fatalf("third request got %d %q, want 429", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Retry-After") == "" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("429 headers: %v", w.Header())
+	}
+}
+
+func TestDeniedWithoutErrorHandler(t *testing.T) {
+	for _, problem := range []bool{false, true} {
+		r := newLimitedEngine(problem)
+		r.ErrorHandler = func(c *gee.Context) {}
+		var w *httptest.ResponseRecorder
+		for i := 0; i < 3; i++ {
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		}
+		if w.Code != http.StatusTooManyRequests {
+			t.Errorf("problem=%v: got %d, want 429", problem, w.Code)
+		}
+		isProblem := w.Header().Get("Content-Type") == "application/problem+json"
+		if isProblem != problem {
+			t.Errorf("problem=%v: got Content-Type %q", problem, w.Header().Get("Content-Type"))
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package gee
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultErrorWriter is where Recovery writes unless configured otherwise
+var DefaultErrorWriter io.Writer = os.Stderr
+
+// RecoveryFunc handles a panic recovered from the chain
+type RecoveryFunc func(c *Context, err any)
+
+// Recovery recovers from panics in the handlers that follow it, logs the
+// stack trace and answers 500 unless the response was already started
+func Recovery() HandlerFunc {
+	return RecoveryWithWriter(defaultErrorWriter())
+}
+
+// CustomRecovery is Recovery with handle writing the response, e.g. a
+// JSON error body. handle is not called once the response was started.
+func CustomRecovery(handle RecoveryFunc) HandlerFunc {
+	return RecoveryWithWriter(defaultErrorWriter(), handle)
+}
+
+func defaultErrorWriter() io.Writer {
+	if Mode() == TestMode {
+		return io.Discard
+	}
+	return DefaultErrorWriter
+}
+
+// RecoveryWithWriter is Recovery logging to out, optionally with a custom
+// handler. The engine logger is used instead of out when one is set.
+// http.ErrAbortHandler is panicked again so net/http aborts the response.
+func RecoveryWithWriter(out io.Writer, recovery ...RecoveryFunc) HandlerFunc {
+	handle := defaultRecovery
+	if len(recovery) > 0 {
+		handle = recovery[0]
+	}
+	return func(c *Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			brokenPipe := isBrokenPipe(err)
+			var trace string
+			if !brokenPipe {
+				trace = string(stack(3))
+			}
+			if c.engine != nil && c.engine.logger != nil {
+				c.Logger().Error("panic recovered", slog.Any("error", err), slog.String("stack", trace))
+			} else if brokenPipe {
+				fmt.Fprintf(out, "[Recovery] %s connection lost: %v\n", time.Now().Format("2006/01/02 - 15:04:05"), err)
+			} else {
+				fmt.Fprintf(out, "[Recovery] %s panic recovered: %s %s\n%v\n%s\n",
+					time.Now().Format("2006/01/02 - 15:04:05"), c.Method, c.Req.URL.Path, err, trace)
+			}
+			if brokenPipe || c.Written() {
+				// the client is gone, writing would fail again, or a part
+				// of the response is sent and a 500 would only corrupt it
+				c.Abort()
+				return
+			}
+			handle(c, err)
+			c.Abort()
+		}()
+		c.Next()
+	}
+}
+
+func defaultRecovery(c *Context, err any) {
+	c.String(http.StatusInternalServerError, "500 INTERNAL SERVER ERROR\n")
+}
+
+// isBrokenPipe reports whether err comes from writing to a client that
+// closed the connection
+func isBrokenPipe(err any) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	if errors.Is(e, syscall.EPIPE) || errors.Is(e, syscall.ECONNRESET) {
+		return true
+	}
+	msg := strings.ToLower(e.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
+
+// stack formats the stack of the panicking goroutine, skipping the runtime
+// frames, with the source line of every frame in debug mode
+func stack(skip int) []byte {
+	pc := make([]uintptr, 64)
+	n := runtime.Callers(skip, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	buf := new(bytes.Buffer)
+	lines := map[string][][]byte{}
+	verbose := IsDebugging()
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			fmt.Fprintf(buf, "%s:%d (%s)\n", frame.File, frame.Line, frame.Function)
+			if verbose {
+				src, ok := lines[frame.File]
+				if !ok {
+					if data, err := os.ReadFile(frame.File); err == nil {
+						src = bytes.Split(data, []byte{'\n'})
+					}
+					lines[frame.File] = src
+				}
+				if i := frame.Line - 1; i >= 0 && i < len(src) {
+					fmt.Fprintf(buf, "\t%s\n", bytes.TrimSpace(src[i]))
+				}
+			}
+		}
+		if !more || strings.HasPrefix(frame.Function, "net/http.") {
+			break
+		}
+	}
+	return buf.Bytes()
+}
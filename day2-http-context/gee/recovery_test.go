@@ -0,0 +1,65 @@
+package gee
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecovery(t *testing.T) {
+	var out bytes.Buffer
+	r := New()
+	r.Use(RecoveryWithWriter(&out))
+	r.GET("/", func(c *Context) {
+		panic("boom")
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "500 INTERNAL SERVER ERROR\n" {
+		t.Errorf("got %d %q", w.Code, w.Body.String())
+	}
+	if !strings.Contains(out.String(), "panic recovered: GET /") || !strings.Contains(out.String(), "boom") {
+		t.Errorf("log = %q", out.String())
+	}
+}
+
+func TestRecoveryAfterWrite(t *testing.T) {
+	var out bytes.Buffer
+	handled := false
+	r := New()
+	r.Use(RecoveryWithWriter(&out, func(c *Context, err any) {
+		handled = true
+		c.String(http.StatusInternalServerError, "custom")
+	}))
+	r.GET("/", func(c *Context) {
+		c.Writer.Write([]byte("partial"))
+		panic("boom")
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("got %d %q, want the partial response untouched", w.Code, w.Body.String())
+	}
+	if handled {
+		t.Errorf("the recovery handler ran after the response started")
+	}
+	if !strings.Contains(out.String(), "boom") {
+		t.Errorf("the panic was not logged: %q", out.String())
+	}
+}
+
+func TestRecoveryAbortHandler(t *testing.T) {
+	r := New()
+	r.Use(Recovery())
+	r.GET("/", func(c *Context) {
+		panic(http.ErrAbortHandler)
+	})
+	defer func() {
+		if err := recover(); err != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler panicked again", err)
+		}
+	}()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
@@ -0,0 +1,93 @@
+package gee
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Route is a registered route, it can be named for URL building
+type Route struct {
+	Method  string
+	Pattern string
+	engine  *Engine
+}
+
+// Name registers the route under name for Engine.URL and RedirectToRoute
+func (r *Route) Name(name string) *Route {
+	if r.engine.namedRoutes == nil {
+		r.engine.namedRoutes = make(map[string]*Route)
+	}
+	if _, ok := r.engine.namedRoutes[name]; ok {
+		panic("gee: route name " + name + " is already used")
+	}
+	r.engine.namedRoutes[name] = r
+	return r
+}
+
+// URL builds the path of the route called name. params are key/value
+// pairs filling its :key and *key segments, e.g. URL("user", "id", "42").
+func (engine *Engine) URL(name string, params ...string) (string, error) {
+	route, ok := engine.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("gee: no route named %q", name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("gee: odd number of params for route %q", name)
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+	parts := strings.Split(route.Pattern, "/")
+	for i, part := range parts {
+		if part == "" || (part[0] != ':' && part[0] != '*') {
+			continue
+		}
+		value, ok := values[part[1:]]
+		if !ok {
+			return "", fmt.Errorf("gee: missing param %q for route %q", part[1:], name)
+		}
+		if part[0] == ':' {
+			parts[i] = url.PathEscape(value)
+			continue
+		}
+		segments := strings.Split(strings.TrimPrefix(value, "/"), "/")
+		for j, segment := range segments {
+			segments[j] = url.PathEscape(segment)
+		}
+		parts[i] = strings.Join(segments, "/")
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+// Redirect answers with a redirect to location. code must be one of 301,
+// 302, 303, 307 or 308. Prefer 303 after a POST, and 307 or 308 when the
+// client must repeat the method and body.
+func (c *Context) Redirect(code int, location string) {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		panic(fmt.Sprintf("gee: cannot redirect with status code %d", code))
+	}
+	http.Redirect(c.Writer, c.Req, location, code)
+	c.StatusCode = code
+}
+
+// RedirectToRoute redirects to the named route built with params, with
+// 302 for GET and HEAD and 303 for other methods so the client follows
+// up with a GET
+func (c *Context) RedirectToRoute(name string, params ...string) {
+	location, err := c.engine.URL(name, params...)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "%v\n", err)
+		return
+	}
+	code := http.StatusSeeOther
+	if c.Method == http.MethodGet || c.Method == http.MethodHead {
+		code = http.StatusFound
+	}
+	c.Redirect(code, location)
+}
@@ -0,0 +1,132 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirect(t *testing.T) {
+	for _, code := range []int{301, 302, 303, 307, 308} {
+		r := New()
+		r.GET("/old", func(c *Context) {
+			c.Redirect(code, "/new")
+		})
+		w := get(r, "/old", nil)
+		if w.Code != code || w.Header().Get("Location") != "/new" {
+			t.Errorf("%d: got %d to %q", code, w.Code, w.Header().Get("Location"))
+		}
+	}
+	for _, code := range []int{200, 300, 304, 404} {
+		c := newContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%d: Redirect did not panic", code)
+				}
+			}()
+			c.Redirect(code, "/new")
+		}()
+	}
+}
+
+func TestURL(t *testing.T) {
+	r := New()
+	handler := func(c *Context) {}
+	r.GET("/users/:id/posts/:post", handler).Name("post")
+	r.GET("/files/*path", handler).Name("file")
+	r.GET("/about", handler).Name("about")
+	tests := []struct {
+		name   string
+		params []string
+		want   string
+		ok     bool
+	}{
+		{"post", []string{"id", "42", "post", "7"}, "/users/42/posts/7", true},
+		{"post", []string{"post", "7", "id", "a b/c"}, "/users/a%20b%2Fc/posts/7", true},
+		{"file", []string{"path", "css/app v2.css"}, "/files/css/app%20v2.css", true},
+		{"file", []string{"path", "/css/app.css"}, "/files/css/app.css", true},
+		{"about", nil, "/about", true},
+		{"about", []string{"unused", "x"}, "/about", true},
+		{"post", []string{"id", "42"}, "", false},
+		{"post", []string{"id"}, "", false},
+		{"missing", nil, "", false},
+	}
+	for _, tt := range tests {
+		got, err := r.URL(tt.name, tt.params...)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("URL(%q, %q) = %q, %v, want %q", tt.name, tt.params, got, err, tt.want)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("a route name was registered twice")
+		}
+	}()
+	r.GET("/other", handler).Name("about")
+}
+
+func TestRedirectToRoute(t *testing.T) {
+	r := New()
+	r.GET("/users/:id", func(c *Context) {}).Name("user")
+	r.GET("/go/:id", func(c *Context) {
+		c.RedirectToRoute("user", "id", c.Param("id"))
+	})
+	r.POST("/users", func(c *Context) {
+		c.RedirectToRoute("user", "id", "42")
+	})
+	r.GET("/broken", func(c *Context) {
+		c.RedirectToRoute("nowhere")
+	})
+
+	w := get(r, "/go/ann%20b", nil)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/users/ann%20b" {
+		t.Errorf("GET got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/users", nil))
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/users/42" {
+		t.Errorf("POST got %d to %q, want 303", w.Code, w.Header().Get("Location"))
+	}
+	w = get(r, "/broken", nil)
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Location") != "" {
+		t.Errorf("unknown route got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestHandleContext(t *testing.T) {
+	r := New()
+	var trace []string
+	r.Use(func(c *Context) {
+		trace = append(trace, "mw "+c.Path)
+		c.Next()
+	})
+	r.GET("/old/:id", func(c *Context) {
+		c.Set("from", "old")
+		c.Req.URL.Path = "/new/" + c.Param("id")
+		r.HandleContext(c)
+		trace = append(trace, "back in old")
+	})
+	r.GET("/new/:name", func(c *Context) {
+		_, leaked := c.Get("from")
+		trace = append(trace, "new")
+		c.String(http.StatusOK, "name=%s id=%s leaked=%v route=%s", c.Param("name"), c.Param("id"), leaked, c.FullPath())
+	})
+
+	w := get(r, "/old/7", nil)
+	want := "name=7 id= leaked=false route=/new/:name"
+	if w.Code != http.StatusOK || w.Body.String() != want {
+		t.Errorf("got %d %q, want %q", w.Code, w.Body.String(), want)
+	}
+	wantTrace := []string{"mw /old/7", "mw /new/7", "new", "back in old"}
+	if len(trace) != len(wantTrace) {
+		t.Fatalf("trace = %q, want %q", trace, wantTrace)
+	}
+	for i := range trace {
+		if trace[i] != wantTrace[i] {
+			t.Errorf("trace = %q, want %q", trace, wantTrace)
+			break
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package render
+
+import "net/http"
+
+// Data renders raw bytes with an optional ContentType
+type Data struct {
+	ContentType string
+	Data        []byte
+}
+
+func (r Data) Render(w http.ResponseWriter) (err error) {
+	r.WriteContentType(w)
+	_, err = w.Write(r.Data)
+	return
+}
+
+func (r Data) WriteContentType(w http.ResponseWriter) {
+	if r.ContentType != "" {
+		writeContentType(w, []string{r.ContentType})
+	}
+}
@@ -0,0 +1,188 @@
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+var htmlContentType = []string{"text/html; charset=utf-8"}
+
+// Delims are the action delimiters used when parsing templates
+type Delims struct {
+	Left  string
+	Right string
+}
+
+// HTMLRender produces the Render for a named template
+type HTMLRender interface {
+	Instance(name string, data interface{}) Render
+}
+
+// HTMLLoader describes where templates come from.
+// Every page is parsed on top of its own copy of the Layouts, so pages may
+// override the same {{block}} of a layout without clashing.
+type HTMLLoader struct {
+	// FS is the file system to read from, nil means the local disk
+	FS fs.FS
+	// Patterns are glob patterns of page templates
+	Patterns []string
+	// Files are page templates listed one by one
+	Files []string
+	// Layouts are glob patterns of layouts and partials shared by every page
+	Layouts []string
+	Delims  Delims
+	FuncMap template.FuncMap
+}
+
+// Load parses the templates, keyed by the base name of each page file
+func (l HTMLLoader) Load() (map[string]*template.Template, error) {
+	layouts, err := l.glob(l.Layouts)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := l.glob(l.Patterns)
+	if err != nil {
+		return nil, err
+	}
+	pages = append(pages, l.Files...)
+
+	shared := template.New("").Delims(l.Delims.Left, l.Delims.Right).Funcs(l.FuncMap)
+	isLayout := make(map[string]bool, len(layouts))
+	for _, file := range layouts {
+		isLayout[file] = true
+		if err := l.parse(shared, file); err != nil {
+			return nil, err
+		}
+	}
+
+	templates := make(map[string]*template.Template)
+	for _, file := range pages {
+		if isLayout[file] {
+			continue
+		}
+		page, err := shared.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if err := l.parse(page, file); err != nil {
+			return nil, err
+		}
+		templates[l.base(file)] = page
+	}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("render: no html templates matched %q %q", l.Patterns, l.Files)
+	}
+	return templates, nil
+}
+
+func (l HTMLLoader) glob(patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		var (
+			matches []string
+			err     error
+		)
+		if l.FS != nil {
+			matches, err = fs.Glob(l.FS, pattern)
+		} else {
+			matches, err = filepath.Glob(pattern)
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+func (l HTMLLoader) parse(t *template.Template, file string) error {
+	var (
+		content []byte
+		err     error
+	)
+	if l.FS != nil {
+		content, err = fs.ReadFile(l.FS, file)
+	} else {
+		content, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = t.New(l.base(file)).Parse(string(content))
+	return err
+}
+
+func (l HTMLLoader) base(file string) string {
+	if l.FS != nil {
+		return path.Base(file)
+	}
+	return filepath.Base(file)
+}
+
+// HTMLProduction renders templates parsed once at startup
+type HTMLProduction struct {
+	Templates map[string]*template.Template
+}
+
+// HTMLDebug re-parses the templates for every request so edits show up at once
+type HTMLDebug struct {
+	Loader HTMLLoader
+}
+
+// HTML renders the template Name of Template
+type HTML struct {
+	Template *template.Template
+	Name     string
+	Data     interface{}
+}
+
+// HTMLError is returned by an HTMLRender for a template that could not be
+// found or parsed
+type HTMLError struct {
+	Err error
+}
+
+func (r HTMLProduction) Instance(name string, data interface{}) Render {
+	return lookupHTML(r.Templates, name, data)
+}
+
+func (r HTMLDebug) Instance(name string, data interface{}) Render {
+	templates, err := r.Loader.Load()
+	if err != nil {
+		return HTMLError{Err: err}
+	}
+	return lookupHTML(templates, name, data)
+}
+
+func lookupHTML(templates map[string]*template.Template, name string, data interface{}) Render {
+	t, ok := templates[name]
+	if !ok {
+		return HTMLError{Err: fmt.Errorf("render: html template %q is not defined", name)}
+	}
+	return HTML{Template: t, Name: name, Data: data}
+}
+
+func (r HTML) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	if r.Name == "" {
+		return r.Template.Execute(w, r.Data)
+	}
+	return r.Template.ExecuteTemplate(w, r.Name, r.Data)
+}
+
+func (r HTML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, htmlContentType)
+}
+
+func (r HTMLError) Render(w http.ResponseWriter) error {
+	return r.Err
+}
+
+func (r HTMLError) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, htmlContentType)
+}
@@ -0,0 +1,109 @@
+package render
+
+import (
+	"html/template"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func renderHTML(t *testing.T, r HTMLRender, name string, data interface{}) string {
+	t.Helper()
+	instance := r.Instance(name, data)
+	if e, ok := instance.(HTMLError); ok {
+		t.Fatalf("%s: %v", name, e.Err)
+	}
+	w := httptest.NewRecorder()
+	if err := instance.Render(w); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return w.Body.String()
+}
+
+func TestHTMLLayouts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.tmpl": {Data: []byte(`{{define "base"}}<title>{{block "title" .}}site{{end}}</title>{{template "nav"}}{{block "content" .}}{{end}}{{end}}`)},
+		"layouts/nav.tmpl":  {Data: []byte(`{{define "nav"}}<nav/>{{end}}`)},
+		"pages/home.tmpl":   {Data: []byte(`{{template "base" .}}{{define "content"}}home {{.}}{{end}}`)},
+		"pages/about.tmpl":  {Data: []byte(`{{template "base" .}}{{define "title"}}about{{end}}{{define "content"}}about {{.}}{{end}}`)},
+	}
+	templates, err := HTMLLoader{FS: fsys, Patterns: []string{"pages/*.tmpl"}, Layouts: []string{"layouts/*.tmpl"}}.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := HTMLProduction{Templates: templates}
+	// both pages override the same blocks without clashing
+	tests := map[string]string{
+		"home.tmpl":  "<title>site</title><nav/>home ann",
+		"about.tmpl": "<title>about</title><nav/>about ann",
+	}
+	for name, want := range tests {
+		if got := renderHTML(t, r, name, "ann"); got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+	if _, ok := templates["base.tmpl"]; ok {
+		t.Errorf("a layout was loaded as a page")
+	}
+	if e, ok := r.Instance("missing.tmpl", nil).(HTMLError); !ok || e.Err == nil {
+		t.Errorf("missing template did not report an error")
+	}
+}
+
+func TestHTMLDelimsAndFuncMap(t *testing.T) {
+	fsys := fstest.MapFS{"page.tmpl": {Data: []byte(`{{ raw }} [[upper .]]`)}}
+	templates, err := HTMLLoader{
+		FS:       fsys,
+		Patterns: []string{"*.tmpl"},
+		Delims:   Delims{Left: "[[", Right: "]]"},
+		FuncMap:  template.FuncMap{"upper": strings.ToUpper},
+	}.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := renderHTML(t, HTMLProduction{Templates: templates}, "page.tmpl", "<b>"); got != "{{ raw }} &lt;B&gt;" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestHTMLLoadErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ok.tmpl":  {Data: []byte(`ok`)},
+		"bad.tmpl": {Data: []byte(`{{if}}`)},
+	}
+	loaders := map[string]HTMLLoader{
+		"no match":  {FS: fsys, Patterns: []string{"*.html"}},
+		"bad parse": {FS: fsys, Patterns: []string{"*.tmpl"}},
+		"bad glob":  {FS: fsys, Patterns: []string{"["}},
+	}
+	for name, loader := range loaders {
+		if _, err := loader.Load(); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestHTMLDebugReload(t *testing.T) {
+	fsys := fstest.MapFS{"page.tmpl": {Data: []byte(`v1`)}}
+	loader := HTMLLoader{FS: fsys, Patterns: []string{"*.tmpl"}}
+	templates, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	debug := HTMLDebug{Loader: loader}
+	production := HTMLProduction{Templates: templates}
+
+	fsys["page.tmpl"] = &fstest.MapFile{Data: []byte(`v2`)}
+	if got := renderHTML(t, debug, "page.tmpl", nil); got != "v2" {
+		t.Errorf("debug got %q, want the edited template", got)
+	}
+	if got := renderHTML(t, production, "page.tmpl", nil); got != "v1" {
+		t.Errorf("production got %q, want the template parsed at startup", got)
+	}
+
+	fsys["page.tmpl"] = &fstest.MapFile{Data: []byte(`{{end}}`)}
+	if e, ok := debug.Instance("page.tmpl", nil).(HTMLError); !ok || e.Err == nil {
+		t.Errorf("a broken edit did not report an error")
+	}
+}
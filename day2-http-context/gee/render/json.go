@@ -0,0 +1,172 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"unicode/utf8"
+)
+
+// DefaultSecureJSONPrefix is prepended by SecureJSON to defeat JSON hijacking
+const DefaultSecureJSONPrefix = "while(1);"
+
+// ErrInvalidCallback is returned by JsonpJSON for an unsafe callback name
+var ErrInvalidCallback = errors.New("render: invalid JSONP callback")
+
+var (
+	jsonContentType      = []string{"application/json; charset=utf-8"}
+	jsonpContentType     = []string{"application/javascript; charset=utf-8"}
+	jsonASCIIContentType = []string{"application/json"}
+
+	// callbackPattern accepts dotted JavaScript identifiers such as jQuery.cb_1
+	callbackPattern = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*(\.[a-zA-Z_$][a-zA-Z0-9_$]*)*$`)
+)
+
+// JSON renders data as JSON
+type JSON struct {
+	Data interface{}
+}
+
+// IndentedJSON renders data as human readable JSON
+type IndentedJSON struct {
+	Data interface{}
+}
+
+// SecureJSON renders data as JSON prefixed with Prefix
+type SecureJSON struct {
+	Prefix string
+	Data   interface{}
+}
+
+// JsonpJSON renders data as a JavaScript call of Callback
+type JsonpJSON struct {
+	Callback string
+	Data     interface{}
+}
+
+// AsciiJSON renders data as JSON with every non-ASCII rune escaped
+type AsciiJSON struct {
+	Data interface{}
+}
+
+// PureJSON renders data as JSON without escaping HTML characters
+type PureJSON struct {
+	Data interface{}
+}
+
+// ValidCallback reports whether name is safe to use as a JSONP callback
+func ValidCallback(name string) bool {
+	return callbackPattern.MatchString(name)
+}
+
+func (r JSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	jsonBytes, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(jsonBytes)
+	return err
+}
+
+func (r JSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+func (r IndentedJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	jsonBytes, err := json.MarshalIndent(r.Data, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(jsonBytes)
+	return err
+}
+
+func (r IndentedJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+func (r SecureJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	jsonBytes, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	prefix := r.Prefix
+	if prefix == "" {
+		prefix = DefaultSecureJSONPrefix
+	}
+	if _, err = w.Write([]byte(prefix)); err != nil {
+		return err
+	}
+	_, err = w.Write(jsonBytes)
+	return err
+}
+
+func (r SecureJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+func (r JsonpJSON) Render(w http.ResponseWriter) error {
+	if r.Callback == "" {
+		return JSON{Data: r.Data}.Render(w)
+	}
+	if !ValidCallback(r.Callback) {
+		return ErrInvalidCallback
+	}
+	r.WriteContentType(w)
+	jsonBytes, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	// the leading comment stops the Rosetta Flash family of attacks
+	_, err = fmt.Fprintf(w, "/**/ typeof %s === 'function' && %s(%s);", r.Callback, r.Callback, jsonBytes)
+	return err
+}
+
+func (r JsonpJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonpContentType)
+}
+
+func (r AsciiJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	jsonBytes, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	var buffer bytes.Buffer
+	for len(jsonBytes) > 0 {
+		r, size := utf8.DecodeRune(jsonBytes)
+		if r < utf8.RuneSelf {
+			buffer.WriteByte(jsonBytes[0])
+		} else if r <= 0xFFFF {
+			fmt.Fprintf(&buffer, `\u%04x`, r)
+		} else {
+			// runes outside the BMP are written as a UTF-16 surrogate pair
+			r -= 0x10000
+			fmt.Fprintf(&buffer, `\u%04x\u%04x`, 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+		}
+		jsonBytes = jsonBytes[size:]
+	}
+	_, err = w.Write(buffer.Bytes())
+	return err
+}
+
+func (r AsciiJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonASCIIContentType)
+}
+
+func (r PureJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(r.Data)
+}
+
+func (r PureJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
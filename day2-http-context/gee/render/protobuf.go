@@ -0,0 +1,43 @@
+package render
+
+import (
+	"fmt"
+	"net/http"
+)
+
+var protobufContentType = []string{"application/x-protobuf"}
+
+// Marshaler is satisfied by generated protobuf messages
+type Marshaler interface {
+	Marshal() ([]byte, error)
+}
+
+// ProtoBuf renders already encoded protobuf bytes, or a Marshaler
+type ProtoBuf struct {
+	Data interface{}
+}
+
+func (r ProtoBuf) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	var (
+		bytes []byte
+		err   error
+	)
+	switch data := r.Data.(type) {
+	case []byte:
+		bytes = data
+	case Marshaler:
+		bytes, err = data.Marshal()
+	default:
+		err = fmt.Errorf("render: cannot encode %T as protobuf", r.Data)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bytes)
+	return err
+}
+
+func (r ProtoBuf) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, protobufContentType)
+}
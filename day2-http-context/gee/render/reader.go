@@ -0,0 +1,24 @@
+package render
+
+import (
+	"io"
+	"net/http"
+)
+
+// Reader streams the content of Reader without buffering it in memory
+type Reader struct {
+	ContentType string
+	Reader      io.Reader
+}
+
+func (r Reader) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	_, err := io.Copy(w, r.Reader)
+	return err
+}
+
+func (r Reader) WriteContentType(w http.ResponseWriter) {
+	if r.ContentType != "" {
+		writeContentType(w, []string{r.ContentType})
+	}
+}
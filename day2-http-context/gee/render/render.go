@@ -0,0 +1,41 @@
+package render
+
+import "net/http"
+
+// Render is implemented by every response format (JSON, XML, YAML, ...).
+// Custom formats only need to implement it to be used with Context.Render.
+type Render interface {
+	// Render writes the response body.
+	Render(http.ResponseWriter) error
+	// WriteContentType writes the Content-Type header.
+	WriteContentType(w http.ResponseWriter)
+}
+
+var (
+	_ Render = JSON{}
+	_ Render = IndentedJSON{}
+	_ Render = SecureJSON{}
+	_ Render = JsonpJSON{}
+	_ Render = AsciiJSON{}
+	_ Render = PureJSON{}
+	_ Render = XML{}
+	_ Render = YAML{}
+	_ Render = ProtoBuf{}
+	_ Render = String{}
+	_ Render = Data{}
+	_ Render = Reader{}
+	_ Render = SSEvent{}
+	_ Render = HTML{}
+	_ Render = HTMLError{}
+
+	_ HTMLRender = HTMLProduction{}
+	_ HTMLRender = HTMLDebug{}
+)
+
+// writeContentType sets Content-Type unless the handler already chose one
+func writeContentType(w http.ResponseWriter, value []string) {
+	header := w.Header()
+	if val := header["Content-Type"]; len(val) == 0 {
+		header["Content-Type"] = value
+	}
+}
@@ -0,0 +1,146 @@
+package render
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type yamlItem struct {
+	Name   string   `yaml:"name"`
+	Tags   []string `json:"tags,omitempty"`
+	Skip   int      `yaml:"-"`
+	hidden int
+}
+
+func TestYAML(t *testing.T) {
+	var null *int
+	data := map[string]interface{}{
+		"a":   yamlItem{Name: "true", Tags: []string{"- dash", "a: b"}},
+		"b":   []interface{}{1, "two", yamlItem{Name: "x", Skip: 1}, []int{}},
+		"e":   map[string]int{},
+		"f":   1.5,
+		"n":   null,
+		"num": "42",
+		"s":   "",
+		"t":   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	want := `a:
+  name: "true"
+  tags:
+    - "- dash"
+    - "a: b"
+b:
+  - 1
+  - two
+  -
+    name: x
+  - []
+e: {}
+f: 1.5
+n: null
+num: "42"
+s: ""
+t: 2024-01-02T03:04:05Z
+`
+	w := httptest.NewRecorder()
+	if err := (YAML{Data: data}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+	if w.Body.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", w.Body.String(), want)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/yaml; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	for _, data := range []interface{}{make(chan int), map[string]interface{}{"f": func() {}}, map[[2]int]int{{1, 2}: 3}} {
+		w := httptest.NewRecorder()
+		if err := (YAML{Data: data}).Render(w); err == nil {
+			t.Errorf("%T: no error", data)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("%T: wrote %q before failing", data, w.Body.String())
+		}
+	}
+}
+
+func TestAsciiJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := (AsciiJSON{Data: map[string]string{"s": "aé中😀<"}}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+	// U+1F600 is outside the BMP and needs a surrogate pair
+	want := `{"s":"a\u00e9\u4e2d\ud83d\ude00\u003c"}`
+	if w.Body.String() != want {
+		t.Errorf("got %s, want %s", w.Body.String(), want)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
+
+func TestJsonpJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := (JsonpJSON{Callback: "jQuery.cb_1", Data: []int{1}}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+	want := "/**/ typeof jQuery.cb_1 === 'function' && jQuery.cb_1([1]);"
+	if w.Body.String() != want {
+		t.Errorf("got %s, want %s", w.Body.String(), want)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/javascript; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	for _, callback := range []string{"alert(1)", "a;b", "1cb", "cb.", "a..b", "cb\n", "<script>"} {
+		w := httptest.NewRecorder()
+		err := (JsonpJSON{Callback: callback, Data: []int{1}}).Render(w)
+		if !errors.Is(err, ErrInvalidCallback) || w.Body.Len() != 0 {
+			t.Errorf("%q: got %v and %q", callback, err, w.Body.String())
+		}
+	}
+
+	w = httptest.NewRecorder()
+	if err := (JsonpJSON{Data: []int{1}}).Render(w); err != nil || w.Body.String() != "[1]" {
+		t.Errorf("without callback got %q %v, want plain JSON", w.Body.String(), err)
+	}
+}
+
+func TestSecureJSON(t *testing.T) {
+	tests := map[string]string{
+		"":        "while(1);[1,2]",
+		")]}',\n": ")]}',\n[1,2]",
+	}
+	for prefix, want := range tests {
+		w := httptest.NewRecorder()
+		if err := (SecureJSON{Prefix: prefix, Data: []int{1, 2}}).Render(w); err != nil {
+			t.Fatal(err)
+		}
+		if w.Body.String() != want {
+			t.Errorf("prefix %q: got %q, want %q", prefix, w.Body.String(), want)
+		}
+	}
+}
+
+func TestPureJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := (PureJSON{Data: map[string]string{"html": "<b>&</b>"}}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"html\":\"<b>&</b>\"}\n"; w.Body.String() != want {
+		t.Errorf("got %q, want %q", w.Body.String(), want)
+	}
+}
+
+func TestWriteContentTypeKeepsHandlerChoice(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Type", "application/problem+json")
+	if err := (JSON{Data: 1}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
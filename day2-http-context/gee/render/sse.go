@@ -0,0 +1,91 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var sseContentType = []string{"text/event-stream"}
+
+// fieldReplacer keeps a value on a single line, as required for event and id
+var fieldReplacer = strings.NewReplacer("\n", "", "\r", "", "\x00", "")
+
+// SSEvent is one Server-Sent Events frame. Data is written as is when it is
+// a string or []byte and encoded as JSON otherwise. A frame without Data is
+// not dispatched by the browser, which suits retry hints and Comment
+// heartbeats.
+type SSEvent struct {
+	Event   string
+	ID      string
+	Retry   uint
+	Data    interface{}
+	Comment string
+}
+
+func (r SSEvent) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return r.Encode(w)
+}
+
+func (r SSEvent) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, sseContentType)
+	header := w.Header()
+	if header.Get("Cache-Control") == "" {
+		header.Set("Cache-Control", "no-cache")
+	}
+}
+
+// Encode writes the frame to w, one data: line per line of Data
+func (r SSEvent) Encode(w io.Writer) error {
+	var b strings.Builder
+	if r.Comment != "" {
+		for _, line := range splitLines(r.Comment) {
+			b.WriteString(": " + line + "\n")
+		}
+	}
+	if r.ID != "" {
+		b.WriteString("id: " + fieldReplacer.Replace(r.ID) + "\n")
+	}
+	if r.Event != "" {
+		b.WriteString("event: " + fieldReplacer.Replace(r.Event) + "\n")
+	}
+	if r.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatUint(uint64(r.Retry), 10) + "\n")
+	}
+	if r.Data != nil {
+		data, err := sseData(r.Data)
+		if err != nil {
+			return err
+		}
+		for _, line := range splitLines(data) {
+			b.WriteString("data: " + line + "\n")
+		}
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// splitLines splits s on any of the three line endings the spec allows
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.Split(s, "\n")
+}
+
+func sseData(data interface{}) (string, error) {
+	switch v := data.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+	jsonBytes, err := json.Marshal(data)
+	return string(jsonBytes), err
+}
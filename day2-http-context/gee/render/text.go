@@ -0,0 +1,28 @@
+package render
+
+import (
+	"fmt"
+	"net/http"
+)
+
+var plainContentType = []string{"text/plain; charset=utf-8"}
+
+// String renders Format as plain text, formatted with Data when given
+type String struct {
+	Format string
+	Data   []interface{}
+}
+
+func (r String) Render(w http.ResponseWriter) (err error) {
+	r.WriteContentType(w)
+	if len(r.Data) > 0 {
+		_, err = fmt.Fprintf(w, r.Format, r.Data...)
+		return
+	}
+	_, err = w.Write([]byte(r.Format))
+	return
+}
+
+func (r String) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, plainContentType)
+}
@@ -0,0 +1,241 @@
+package render
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var yamlContentType = []string{"application/yaml; charset=utf-8"}
+
+// YAML renders data as a YAML document.
+// The emitter is intentionally small: it covers maps, slices, structs
+// (honouring `yaml` and `json` tags) and scalars, which is what handlers
+// put into responses in practice.
+type YAML struct {
+	Data interface{}
+}
+
+func (r YAML) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	var buf bytes.Buffer
+	if err := emitYAML(&buf, reflect.ValueOf(r.Data), 0); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (r YAML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, yamlContentType)
+}
+
+// yamlField is a key/value pair of a mapping in output order
+type yamlField struct {
+	key   string
+	value reflect.Value
+}
+
+// emitYAML writes v as a block node indented by indent spaces
+func emitYAML(buf *bytes.Buffer, v reflect.Value, indent int) error {
+	v = indirect(v)
+	if !v.IsValid() {
+		buf.WriteString("null\n")
+		return nil
+	}
+	if scalar, ok, err := yamlScalar(v); ok || err != nil {
+		if err != nil {
+			return err
+		}
+		buf.WriteString(scalar + "\n")
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Struct:
+		fields, err := yamlFields(v)
+		if err != nil {
+			return err
+		}
+		if len(fields) == 0 {
+			buf.WriteString("{}\n")
+			return nil
+		}
+		for _, f := range fields {
+			buf.WriteString(strings.Repeat(" ", indent) + yamlString(f.key) + ":")
+			if err := emitYAMLChild(buf, f.value, indent+2); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			buf.WriteString("[]\n")
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			buf.WriteString(strings.Repeat(" ", indent) + "-")
+			if err := emitYAMLChild(buf, v.Index(i), indent+2); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("render: cannot encode %s as YAML", v.Type())
+	}
+	return nil
+}
+
+// emitYAMLChild writes the value following a "key:" or "-" marker
+func emitYAMLChild(buf *bytes.Buffer, v reflect.Value, indent int) error {
+	v = indirect(v)
+	if !v.IsValid() {
+		buf.WriteString(" null\n")
+		return nil
+	}
+	scalar, ok, err := yamlScalar(v)
+	if err != nil {
+		return err
+	}
+	if ok {
+		buf.WriteString(" " + scalar + "\n")
+		return nil
+	}
+	if isEmptyCollection(v) {
+		buf.WriteString(" ")
+		return emitYAML(buf, v, 0)
+	}
+	buf.WriteString("\n")
+	return emitYAML(buf, v, indent)
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isEmptyCollection(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return v.Len() == 0
+	case reflect.Struct:
+		fields, err := yamlFields(v)
+		return err == nil && len(fields) == 0
+	}
+	return false
+}
+
+// yamlScalar formats v when it is a scalar, ok is false for collections
+func yamlScalar(v reflect.Value) (string, bool, error) {
+	if v.CanInterface() {
+		if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			return yamlString(string(text)), true, err
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return yamlString(v.String()), true, nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), true, nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return yamlString(string(v.Bytes())), true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// yamlFields lists the entries of a map (sorted by key) or a struct
+func yamlFields(v reflect.Value) ([]yamlField, error) {
+	var fields []yamlField
+	if v.Kind() == reflect.Map {
+		for _, key := range v.MapKeys() {
+			name, err := yamlKey(indirect(key))
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, yamlField{key: name, value: v.MapIndex(key)})
+		}
+		sort.Slice(fields, func(i, j int) bool { return fields[i].key < fields[j].key })
+		return fields, nil
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, omitEmpty := yamlFieldName(sf)
+		if name == "-" {
+			continue
+		}
+		if omitEmpty && v.Field(i).IsZero() {
+			continue
+		}
+		fields = append(fields, yamlField{key: name, value: v.Field(i)})
+	}
+	return fields, nil
+}
+
+// yamlKey returns the unquoted text of a map key
+func yamlKey(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	name, ok, err := yamlScalar(key)
+	if !ok || err != nil {
+		return "", fmt.Errorf("render: cannot encode map key %s as YAML", key.Type())
+	}
+	return name, nil
+}
+
+func yamlFieldName(sf reflect.StructField) (string, bool) {
+	tag, ok := sf.Tag.Lookup("yaml")
+	if !ok {
+		tag, ok = sf.Tag.Lookup("json")
+	}
+	if !ok {
+		return strings.ToLower(sf.Name), false
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = strings.ToLower(sf.Name)
+	}
+	return name, strings.Contains(opts, "omitempty")
+}
+
+// yamlString quotes s when a plain scalar would be read back differently
+func yamlString(s string) string {
+	if s == "" {
+		return `""`
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@` ") ||
+		strings.HasSuffix(s, " ") ||
+		strings.Contains(s, ": ") ||
+		strings.Contains(s, " #") ||
+		strings.ContainsAny(s, "\n\r\t\\") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -2,26 +2,120 @@ package gee
 
 import (
 	"net/http"
+	"sort"
+	"strings"
 )
 
 type router struct {
+	roots    map[string]*node
 	handlers map[string]HandlerFunc
+	noRoute  HandlerFunc
 }
 
+// roots key eg, roots['GET'] roots['POST']
+// handlers key eg, handlers['GET-/p/:lang/doc'], handlers['POST-/p/book']
+
 func newRouter() *router {
-	return &router{handlers: make(map[string]HandlerFunc)}
+	return &router{
+		roots:    make(map[string]*node),
+		handlers: make(map[string]HandlerFunc),
+	}
+}
+
+// Only one * is allowed
+func parsePattern(pattern string) []string {
+	vs := strings.Split(pattern, "/")
+
+	parts := make([]string, 0)
+	for _, item := range vs {
+		if item != "" {
+			parts = append(parts, item)
+			if item[0] == '*' {
+				break
+			}
+		}
+	}
+	return parts
 }
 
 func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
+	parts := parsePattern(pattern)
+
 	key := method + "-" + pattern
+	if _, ok := r.roots[method]; !ok {
+		r.roots[method] = &node{}
+	}
+	r.roots[method].insert(pattern, parts, 0)
 	r.handlers[key] = handler
 }
 
+func (r *router) getRoute(method string, path string) (*node, map[string]string) {
+	searchParts := parsePattern(path)
+	params := make(map[string]string)
+	root, ok := r.roots[method]
+
+	if !ok {
+		return nil, nil
+	}
+
+	n := root.search(searchParts, 0)
+
+	if n != nil {
+		parts := parsePattern(n.pattern)
+		for index, part := range parts {
+			if part[0] == ':' {
+				params[part[1:]] = searchParts[index]
+			}
+			if part[0] == '*' && len(part) > 1 {
+				params[part[1:]] = strings.Join(searchParts[index:], "/")
+				break
+			}
+		}
+		return n, params
+	}
+
+	return nil, nil
+}
+
 func (r *router) handle(c *Context) {
-	key := c.Method + "-" + c.Path
-	if handler, ok := r.handlers[key]; ok {
-		handler(c)
+	n, params := r.getRoute(c.Method, c.Path)
+	if n != nil {
+		c.Params = params
+		c.fullPath = n.pattern
+		key := c.Method + "-" + n.pattern
+		c.handlers = append(c.handlers, r.handlers[key])
+	} else if allow := r.methodNotAllowed(c); len(allow) > 0 {
+		c.handlers = append(c.handlers, func(c *Context) {
+			c.SetHeader("Allow", strings.Join(allow, ", "))
+			c.statusResponse(http.StatusMethodNotAllowed, c.Method+" "+c.Path)
+		})
+	} else if r.noRoute != nil {
+		c.handlers = append(c.handlers, r.noRoute)
 	} else {
-		c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+		c.handlers = append(c.handlers, notFound)
 	}
+	c.Next()
+}
+
+// methodNotAllowed returns the other methods having a route for the path
+// of c, when the engine handles 405
+func (r *router) methodNotAllowed(c *Context) []string {
+	if c.engine == nil || !c.engine.HandleMethodNotAllowed {
+		return nil
+	}
+	var allow []string
+	for m := range r.roots {
+		if m == c.Method {
+			continue
+		}
+		if n, _ := r.getRoute(m, c.Path); n != nil {
+			allow = append(allow, m)
+		}
+	}
+	sort.Strings(allow)
+	return allow
+}
+
+func notFound(c *Context) {
+	c.statusResponse(http.StatusNotFound, c.Path)
 }
@@ -0,0 +1,97 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestRouter(patterns ...string) *router {
+	r := newRouter()
+	for _, pattern := range patterns {
+		r.addRoute("GET", pattern, nil)
+	}
+	return r
+}
+
+func TestGetRoutePrecedence(t *testing.T) {
+	// registration order must not matter
+	for _, patterns := range [][]string{
+		{"/p/:lang", "/p/book", "/p/*path", "/p/:lang/doc"},
+		{"/p/*path", "/p/:lang/doc", "/p/book", "/p/:lang"},
+	} {
+		r := newTestRouter(patterns...)
+		cases := []struct {
+			path, pattern string
+			params        map[string]string
+		}{
+			{"/p/book", "/p/book", map[string]string{}},
+			{"/p/go", "/p/:lang", map[string]string{"lang": "go"}},
+			{"/p/go/doc", "/p/:lang/doc", map[string]string{"lang": "go"}},
+			{"/p/go/src/main.go", "/p/*path", map[string]string{"path": "go/src/main.go"}},
+		}
+		for _, tc := range cases {
+			n, params := r.getRoute("GET", tc.path)
+			if n == nil {
+				t.Errorf("%v: %s matched no route", patterns, tc.path)
+				continue
+			}
+			if n.pattern != tc.pattern {
+				t.Errorf("%v: %s matched %s, want %s", patterns, tc.path, n.pattern, tc.pattern)
+			}
+			for k, v := range tc.params {
+				if params[k] != v {
+					t.Errorf("%v: %s param %s = %q, want %q", patterns, tc.path, k, params[k], v)
+				}
+			}
+		}
+	}
+}
+
+func TestGetRouteNoMatch(t *testing.T) {
+	r := newTestRouter("/p/:lang/doc", "/hello")
+	for _, path := range []string{"/p/go", "/hello/x", "/"} {
+		if n, _ := r.getRoute("GET", path); n != nil {
+			t.Errorf("%s matched %s", path, n.pattern)
+		}
+	}
+	if n, _ := r.getRoute("POST", "/hello"); n != nil {
+		t.Errorf("POST /hello matched %s", n.pattern)
+	}
+}
+
+func TestAddRouteConflict(t *testing.T) {
+	for _, patterns := range [][]string{
+		{"/p/:lang", "/p/:name"},
+		{"/p/:lang/doc", "/p/:name/src"},
+		{"/f/*path", "/f/*file"},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%v: no panic", patterns)
+				}
+			}()
+			newTestRouter(patterns...)
+		}()
+	}
+}
+
+func TestStaticBesideParam(t *testing.T) {
+	r := New()
+	r.GET("/assets/:name", func(c *Context) {
+		c.String(http.StatusOK, "name=%s", c.Param("name"))
+	})
+	r.StaticFS("/assets", fstest.MapFS{"a/b.js": {Data: []byte("js")}})
+	for path, want := range map[string]string{
+		"/assets/x":      "name=x",
+		"/assets/a/b.js": "js",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Errorf("%s: got %d %q, want %q", path, w.Code, w.Body.String(), want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package sessions
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"gee"
+)
+
+// maxCookieSize is the limit most browsers enforce for one cookie
+const maxCookieSize = 4096
+
+// ErrCookieTooLarge is returned when a session no longer fits in a cookie
+var ErrCookieTooLarge = errors.New("sessions: session is too large for a cookie")
+
+// CookieStore keeps the whole session in the cookie, signed or encrypted
+// with the keys set by Engine.SetCookieKeys. Sessions are only refreshed
+// when saved, and a deleted session can't be revoked before it expires.
+type CookieStore struct {
+	options Options
+	encrypt bool
+}
+
+// NewCookieStore returns a store of signed cookies, readable by the client
+// but tamper-proof
+func NewCookieStore(options Options) *CookieStore {
+	return &CookieStore{options: options}
+}
+
+// NewEncryptedCookieStore returns a store of AES-GCM encrypted cookies
+func NewEncryptedCookieStore(options Options) *CookieStore {
+	return &CookieStore{options: options, encrypt: true}
+}
+
+func (store *CookieStore) Options() Options {
+	return store.options
+}
+
+func (store *CookieStore) Load(c *gee.Context, name string) (*Session, error) {
+	var (
+		raw string
+		err error
+	)
+	if store.encrypt {
+		raw, err = c.EncryptedCookie(name)
+	} else {
+		raw, err = c.SignedCookie(name)
+	}
+	if errors.Is(err, http.ErrNoCookie) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Decode([]byte(raw))
+}
+
+func (store *CookieStore) Save(c *gee.Context, s *Session) error {
+	o := store.options
+	if s.Destroyed() {
+		c.SetCookie(s.Name(), "", -1, o.Path, o.Domain, o.Secure, o.HttpOnly)
+		return nil
+	}
+	data, err := s.Encode()
+	if err != nil {
+		return err
+	}
+	if base64.RawURLEncoding.EncodedLen(len(data))+len(s.Name())+100 > maxCookieSize {
+		return ErrCookieTooLarge
+	}
+	if store.encrypt {
+		return c.SetEncryptedCookie(s.Name(), string(data), o.MaxAge, o.Path, o.Domain, o.Secure, o.HttpOnly)
+	}
+	return c.SetSignedCookie(s.Name(), string(data), o.MaxAge, o.Path, o.Domain, o.Secure, o.HttpOnly)
+}
@@ -0,0 +1,305 @@
+package sessions
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gee"
+)
+
+// backend stores encoded sessions by ID for the server side stores. get
+// also returns the expiry, touch moves it without rewriting the session.
+type backend interface {
+	get(id string) ([]byte, time.Time, bool, error)
+	set(id string, data []byte, ttl time.Duration) error
+	touch(id string, ttl time.Duration) error
+	delete(id string) error
+}
+
+// serverStore keeps sessions on the server, the cookie only holds the ID
+type serverStore struct {
+	options Options
+	backend backend
+}
+
+func (store *serverStore) Options() Options {
+	return store.options
+}
+
+// ttl is how long an unused session is kept
+func (store *serverStore) ttl() time.Duration {
+	switch o := store.options; {
+	case o.IdleTimeout > 0:
+		return o.IdleTimeout
+	case o.MaxAge > 0:
+		return time.Duration(o.MaxAge) * time.Second
+	case o.AbsoluteTimeout > 0:
+		return o.AbsoluteTimeout
+	}
+	return 24 * time.Hour
+}
+
+func (store *serverStore) Load(c *gee.Context, name string) (*Session, error) {
+	id, err := c.Cookie(name)
+	if errors.Is(err, http.ErrNoCookie) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !validID(id) {
+		return nil, ErrInvalidID
+	}
+	data, expires, ok, err := store.backend.get(id)
+	if err != nil || !ok {
+		return nil, err
+	}
+	s, err := Decode(data)
+	if err != nil || s.ID != id {
+		return nil, err
+	}
+	// Touch only moves the expiry, which tells when the session was last used
+	if used := expires.Add(-store.ttl()); used.After(s.AccessedAt) {
+		s.AccessedAt = used
+	}
+	return s, nil
+}
+
+func (store *serverStore) Save(c *gee.Context, s *Session) error {
+	o := store.options
+	if id := s.PreviousID(); id != "" {
+		if err := store.backend.delete(id); err != nil {
+			return err
+		}
+	}
+	if s.Destroyed() {
+		c.SetCookie(s.Name(), "", -1, o.Path, o.Domain, o.Secure, o.HttpOnly)
+		return store.backend.delete(s.ID)
+	}
+	data, err := s.Encode()
+	if err != nil {
+		return err
+	}
+	if err := store.backend.set(s.ID, data, store.ttl()); err != nil {
+		return err
+	}
+	c.SetCookie(s.Name(), s.ID, o.MaxAge, o.Path, o.Domain, o.Secure, o.HttpOnly)
+	return nil
+}
+
+// Touch keeps a session that was read but not saved from hitting its idle
+// timeout. Only the expiry moves, so neither the unsaved changes of s nor
+// a stale copy overwrite what a concurrent request saved.
+func (store *serverStore) Touch(s *Session) error {
+	return store.backend.touch(s.ID, store.ttl())
+}
+
+// MemoryStore keeps sessions in process memory, they are lost on restart
+type MemoryStore struct {
+	serverStore
+}
+
+// memoryBackend is a map of sessions swept for expired entries now and then
+type memoryBackend struct {
+	mu        sync.Mutex
+	items     map[string]memoryItem
+	lastSweep time.Time
+}
+
+type memoryItem struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewMemoryStore is the constructor of sessions.MemoryStore
+func NewMemoryStore(options Options) *MemoryStore {
+	return &MemoryStore{serverStore{
+		options: options,
+		backend: &memoryBackend{items: make(map[string]memoryItem), lastSweep: time.Now()},
+	}}
+}
+
+func (m *memoryBackend) get(id string) ([]byte, time.Time, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[id]
+	if !ok || time.Now().After(item.expires) {
+		delete(m.items, id)
+		return nil, time.Time{}, false, nil
+	}
+	return item.data, item.expires, true, nil
+}
+
+func (m *memoryBackend) set(id string, data []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.items[id] = memoryItem{data: data, expires: now.Add(ttl)}
+	if now.Sub(m.lastSweep) > time.Minute {
+		for key, item := range m.items {
+			if now.After(item.expires) {
+				delete(m.items, key)
+			}
+		}
+		m.lastSweep = now
+	}
+	return nil
+}
+
+func (m *memoryBackend) touch(id string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if item, ok := m.items[id]; ok && !now.After(item.expires) {
+		item.expires = now.Add(ttl)
+		m.items[id] = item
+	}
+	return nil
+}
+
+func (m *memoryBackend) delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.items, id)
+	return nil
+}
+
+// FileStore keeps one file per session in a directory
+type FileStore struct {
+	serverStore
+}
+
+// fileBackend names files after the session ID, expiry is the file mtime
+type fileBackend struct {
+	dir string
+}
+
+// NewFileStore is the constructor of sessions.FileStore, dir is created
+// when missing
+func NewFileStore(dir string, options Options) *FileStore {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		panic(err)
+	}
+	return &FileStore{serverStore{options: options, backend: &fileBackend{dir: dir}}}
+}
+
+// staleTemp is the age after which a temporary file left by a crashed
+// write is removed by Cleanup
+const staleTemp = time.Hour
+
+// Cleanup removes the files of expired sessions and temporary files left
+// behind by interrupted writes, other files in the directory are kept
+func (store *FileStore) Cleanup() error {
+	fb := store.backend.(*fileBackend)
+	entries, err := os.ReadDir(fb.dir)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		name := entry.Name()
+		id, isSession := strings.CutPrefix(name, sessionPrefix)
+		expired := isSession && validID(id) && now.After(info.ModTime())
+		// a temporary file being written has a recent mtime or, just before
+		// its rename, the future expiry, so neither is touched
+		stale := strings.HasPrefix(name, tempPrefix) && now.Sub(info.ModTime()) > staleTemp
+		if expired || stale {
+			os.Remove(filepath.Join(fb.dir, name))
+		}
+	}
+	return nil
+}
+
+const (
+	sessionPrefix = "session_"
+	tempPrefix    = ".tmp_session_"
+)
+
+func (f *fileBackend) path(id string) string {
+	return filepath.Join(f.dir, sessionPrefix+id)
+}
+
+func (f *fileBackend) get(id string) ([]byte, time.Time, bool, error) {
+	path := f.path(id)
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, time.Time{}, false, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	if time.Now().After(info.ModTime()) {
+		os.Remove(path)
+		return nil, time.Time{}, false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	return data, info.ModTime(), true, nil
+}
+
+// set writes through a temporary file so readers never see half a session,
+// the mtime is moved to the expiry time
+func (f *fileBackend) set(id string, data []byte, ttl time.Duration) error {
+	tmp, err := os.CreateTemp(f.dir, tempPrefix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	expires := time.Now().Add(ttl)
+	if err := os.Chtimes(tmp.Name(), expires, expires); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(id))
+}
+
+// touch moves the mtime, a session removed or expired in the meantime is
+// not brought back
+func (f *fileBackend) touch(id string, ttl time.Duration) error {
+	path := f.path(id)
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if now.After(info.ModTime()) {
+		return nil
+	}
+	expires := now.Add(ttl)
+	err = os.Chtimes(path, expires, expires)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (f *fileBackend) delete(id string) error {
+	err := os.Remove(f.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
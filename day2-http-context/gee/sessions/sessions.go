@@ -0,0 +1,298 @@
+// Package sessions keeps per-user state between requests.
+//
+//	r.SetCookieKeys(secret)
+//	r.Use(sessions.Sessions("gee_session", sessions.NewMemoryStore(sessions.Options{})))
+//	r.POST("/login", func(c *gee.Context) {
+//		session := sessions.Default(c)
+//		session.Regenerate()
+//		session.Set("user", c.PostForm("username"))
+//		session.Save()
+//		c.String(http.StatusOK, "welcome")
+//	})
+//
+// Values are encoded with encoding/gob, custom types must be registered
+// with gob.Register.
+package sessions
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gee"
+)
+
+// DefaultKey is the Context key under which Sessions stores the session
+const DefaultKey = "gee/sessions"
+
+// ErrInvalidID is returned by stores for a malformed session ID
+var ErrInvalidID = errors.New("sessions: invalid session id")
+
+// Options configure the session cookie and the lifetime of sessions
+type Options struct {
+	// Path of the cookie, "/" by default
+	Path   string
+	Domain string
+	// MaxAge of the cookie in seconds, 0 makes it a browser session cookie
+	MaxAge   int
+	Secure   bool
+	HttpOnly bool
+	// IdleTimeout expires a session not used for that long, 0 disables it
+	IdleTimeout time.Duration
+	// AbsoluteTimeout expires a session that long after it was created,
+	// whatever its activity, 0 disables it
+	AbsoluteTimeout time.Duration
+}
+
+// Store loads and saves sessions. Load returns nil and no error when the
+// request carries no usable session.
+type Store interface {
+	Load(c *gee.Context, name string) (*Session, error)
+	Save(c *gee.Context, s *Session) error
+	Options() Options
+}
+
+// Session is the state of one user across requests
+type Session struct {
+	ID         string
+	Values     map[string]interface{}
+	CreatedAt  time.Time
+	AccessedAt time.Time
+	// IsNew is true until the session has been saved once
+	IsNew bool
+
+	flashes    map[string][]interface{}
+	name       string
+	store      Store
+	ctx        *gee.Context
+	previousID string
+	destroyed  bool
+	saved      bool
+}
+
+// record is the encoded form of a session
+type record struct {
+	ID         string
+	Values     map[string]interface{}
+	Flashes    map[string][]interface{}
+	CreatedAt  time.Time
+	AccessedAt time.Time
+}
+
+// NewSession creates an empty session called name, stores use it when
+// the request carries no session
+func NewSession(store Store, name string) *Session {
+	now := time.Now()
+	return &Session{
+		ID:         newID(),
+		Values:     make(map[string]interface{}),
+		flashes:    make(map[string][]interface{}),
+		CreatedAt:  now,
+		AccessedAt: now,
+		IsNew:      true,
+		name:       name,
+		store:      store,
+	}
+}
+
+// Sessions is the middleware making the session called name available
+// through Default. The session is loaded on first use.
+func Sessions(name string, store Store) gee.HandlerFunc {
+	return func(c *gee.Context) {
+		lazy := &lazySession{name: name, store: store}
+		c.Set(DefaultKey, lazy)
+		c.Next()
+		// refresh the idle timer of sessions that were read but not saved,
+		// Touch only moves their expiry
+		if s := lazy.session; s != nil && !s.IsNew && !s.saved && !s.destroyed && s.previousID == "" {
+			if toucher, ok := store.(interface{ Touch(*Session) error }); ok {
+				toucher.Touch(s)
+			}
+		}
+	}
+}
+
+// lazySession defers loading until a handler asks for the session
+type lazySession struct {
+	name    string
+	store   Store
+	session *Session
+}
+
+// Default returns the session of the request, Sessions must be in use
+func Default(c *gee.Context) *Session {
+	lazy := c.MustGet(DefaultKey).(*lazySession)
+	if lazy.session == nil {
+		lazy.session = load(c, lazy.name, lazy.store)
+	}
+	return lazy.session
+}
+
+// load restores the session, replacing it with a fresh one when it is
+// missing, broken or timed out
+func load(c *gee.Context, name string, store Store) *Session {
+	s, err := store.Load(c, name)
+	if err == nil && s != nil && expired(s, store.Options()) {
+		fresh := NewSession(store, name)
+		fresh.previousID = s.ID
+		s = fresh
+	}
+	if err != nil || s == nil {
+		s = NewSession(store, name)
+	}
+	s.name, s.store, s.ctx = name, store, c
+	s.AccessedAt = time.Now()
+	return s
+}
+
+func expired(s *Session, options Options) bool {
+	now := time.Now()
+	if options.IdleTimeout > 0 && now.Sub(s.AccessedAt) > options.IdleTimeout {
+		return true
+	}
+	return options.AbsoluteTimeout > 0 && now.Sub(s.CreatedAt) > options.AbsoluteTimeout
+}
+
+// Name returns the name the session was registered under
+func (s *Session) Name() string {
+	return s.name
+}
+
+// PreviousID returns the ID replaced by Regenerate or a timeout, which the
+// store must forget on Save, or ""
+func (s *Session) PreviousID() string {
+	return s.previousID
+}
+
+// Destroyed reports whether Destroy was called, the store must then
+// delete the session on Save
+func (s *Session) Destroyed() bool {
+	return s.destroyed
+}
+
+// Get returns the value stored under key
+func (s *Session) Get(key string) interface{} {
+	return s.Values[key]
+}
+
+// Set stores value under key
+func (s *Session) Set(key string, value interface{}) {
+	s.Values[key] = value
+}
+
+// Delete removes key
+func (s *Session) Delete(key string) {
+	delete(s.Values, key)
+}
+
+// Clear removes every value
+func (s *Session) Clear() {
+	s.Values = make(map[string]interface{})
+}
+
+// AddFlash queues value for the next Flashes call, vars optionally names
+// the queue
+func (s *Session) AddFlash(value interface{}, vars ...string) {
+	key := flashKey(vars)
+	s.flashes[key] = append(s.flashes[key], value)
+}
+
+// Flashes returns and removes the queued flash messages, Save must be
+// called for the removal to stick
+func (s *Session) Flashes(vars ...string) []interface{} {
+	key := flashKey(vars)
+	flashes := s.flashes[key]
+	delete(s.flashes, key)
+	return flashes
+}
+
+func flashKey(vars []string) string {
+	if len(vars) > 0 {
+		return vars[0]
+	}
+	return "_flash"
+}
+
+// Regenerate gives the session a new ID while keeping its values. Call it
+// whenever the privileges change, e.g. on login, to defeat session fixation.
+func (s *Session) Regenerate() {
+	if s.previousID == "" && !s.IsNew {
+		s.previousID = s.ID
+	}
+	s.ID = newID()
+	s.CreatedAt = time.Now()
+}
+
+// Destroy removes the values and makes Save delete the session and its cookie
+func (s *Session) Destroy() {
+	s.Clear()
+	s.flashes = make(map[string][]interface{})
+	s.destroyed = true
+}
+
+// Save persists the session and sets its cookie. It must be called before
+// the response body is written.
+func (s *Session) Save() error {
+	if err := s.store.Save(s.ctx, s); err != nil {
+		return err
+	}
+	s.IsNew = false
+	s.previousID = ""
+	s.saved = true
+	return nil
+}
+
+// Encode serializes the session for a store
+func (s *Session) Encode() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(record{
+		ID:         s.ID,
+		Values:     s.Values,
+		Flashes:    s.flashes,
+		CreatedAt:  s.CreatedAt,
+		AccessedAt: s.AccessedAt,
+	})
+	return buf.Bytes(), err
+}
+
+// Decode restores a session serialized by Encode
+func Decode(data []byte) (*Session, error) {
+	var rec record
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&rec); err != nil {
+		return nil, err
+	}
+	if rec.Values == nil {
+		rec.Values = make(map[string]interface{})
+	}
+	if rec.Flashes == nil {
+		rec.Flashes = make(map[string][]interface{})
+	}
+	return &Session{
+		ID:         rec.ID,
+		Values:     rec.Values,
+		flashes:    rec.Flashes,
+		CreatedAt:  rec.CreatedAt,
+		AccessedAt: rec.AccessedAt,
+	}, nil
+}
+
+// newID returns 32 random bytes, hex encoded
+func newID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// validID reports whether id could have been produced by newID
+func validID(id string) bool {
+	if len(id) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
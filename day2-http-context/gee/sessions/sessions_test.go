@@ -0,0 +1,247 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gee"
+)
+
+func init() {
+	gee.SetMode(gee.TestMode)
+}
+
+// newSessionEngine counts visits in the session, /login regenerates it
+// and /logout destroys it
+func newSessionEngine(store Store) *gee.Engine {
+	r := gee.New()
+	r.SetCookieKeys([]byte("secret"))
+	r.Use(Sessions("gee_session", store))
+	r.GET("/visit", func(c *gee.Context) {
+		s := Default(c)
+		visits, _ := s.Get("visits").(int)
+		s.Set("visits", visits+1)
+		s.Save()
+		c.String(http.StatusOK, "%d", visits+1)
+	})
+	r.GET("/login", func(c *gee.Context) {
+		s := Default(c)
+		s.Regenerate()
+		s.Save()
+		c.String(http.StatusOK, s.ID)
+	})
+	r.GET("/logout", func(c *gee.Context) {
+		s := Default(c)
+		s.Destroy()
+		s.Save()
+		c.String(http.StatusOK, "bye")
+	})
+	return r
+}
+
+// do sends the cookie, if any, and returns the body and the new cookie
+func do(t *testing.T, r *gee.Engine, path string, cookie *http.Cookie) (string, *http.Cookie) {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: got status %d", path, w.Code)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "gee_session" {
+			return w.Body.String(), c
+		}
+	}
+	return w.Body.String(), cookie
+}
+
+func TestStores(t *testing.T) {
+	stores := map[string]Store{
+		"memory":    NewMemoryStore(Options{}),
+		"file":      NewFileStore(t.TempDir(), Options{}),
+		"cookie":    NewCookieStore(Options{}),
+		"encrypted": NewEncryptedCookieStore(Options{}),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			r := newSessionEngine(store)
+			body, cookie := do(t, r, "/visit", nil)
+			if body != "1" || cookie == nil {
+				t.Fatalf("first visit got %q with cookie %v", body, cookie)
+			}
+			if body, cookie = do(t, r, "/visit", cookie); body != "2" {
+				t.Fatalf("second visit got %q, want 2", body)
+			}
+
+			_, cookie = do(t, r, "/logout", cookie)
+			if cookie.MaxAge >= 0 {
+				t.Errorf("logout kept the cookie: %v", cookie)
+			}
+			if body, _ := do(t, r, "/visit", nil); body != "1" {
+				t.Errorf("visit after logout got %q, want a fresh session", body)
+			}
+		})
+	}
+}
+
+func TestRegenerateForgetsOldID(t *testing.T) {
+	r := newSessionEngine(NewMemoryStore(Options{}))
+	_, old := do(t, r, "/visit", nil)
+	id, cookie := do(t, r, "/login", old)
+	if id == old.Value || cookie.Value != id {
+		t.Fatalf("login kept ID %q, cookie %q", old.Value, cookie.Value)
+	}
+	if body, _ := do(t, r, "/visit", cookie); body != "2" {
+		t.Errorf("regenerated session got %q, want its values kept", body)
+	}
+	if body, _ := do(t, r, "/visit", old); body != "1" {
+		t.Errorf("old ID got %q, want it forgotten", body)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	r := newSessionEngine(NewMemoryStore(Options{IdleTimeout: 20 * time.Millisecond}))
+	_, cookie := do(t, r, "/visit", nil)
+	time.Sleep(40 * time.Millisecond)
+	if body, _ := do(t, r, "/visit", cookie); body != "1" {
+		t.Errorf("idle session got %q, want a fresh one", body)
+	}
+}
+
+func TestInvalidID(t *testing.T) {
+	r := newSessionEngine(NewFileStore(t.TempDir(), Options{}))
+	forged := &http.Cookie{Name: "gee_session", Value: "../../etc/passwd"}
+	if body, cookie := do(t, r, "/visit", forged); body != "1" || cookie.Value == forged.Value {
+		t.Errorf("forged ID got %q with cookie %q", body, cookie.Value)
+	}
+}
+
+func TestFileStoreCleanup(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir, Options{})
+	past := time.Now().Add(-2 * time.Hour)
+	future := time.Now().Add(time.Hour)
+	files := map[string]time.Time{
+		"session_" + strings.Repeat("a", 64): past,
+		"session_" + strings.Repeat("b", 64): future,
+		".tmp_session_123":                   past,
+		".tmp_session_456":                   time.Now(),
+		"notes.txt":                          past,
+		"session_backup":                     past,
+	}
+	for name, mtime := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, mtime, mtime)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	removed := map[string]bool{
+		"session_" + strings.Repeat("a", 64): true,
+		".tmp_session_123":                   true,
+	}
+	for name := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if gone := os.IsNotExist(err); gone != removed[name] {
+			t.Errorf("%s: removed = %v, want %v", name, gone, removed[name])
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub")); err != nil {
+		t.Errorf("directory was removed: %v", err)
+	}
+}
+
+func TestTouchKeepsConcurrentSave(t *testing.T) {
+	stores := map[string]Store{
+		"memory": NewMemoryStore(Options{}),
+		"file":   NewFileStore(t.TempDir(), Options{}),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			r := newSessionEngine(store)
+			loaded, release := make(chan struct{}), make(chan struct{})
+			r.GET("/slow-read", func(c *gee.Context) {
+				Default(c).Get("visits")
+				close(loaded)
+				<-release
+			})
+			_, cookie := do(t, r, "/visit", nil)
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				req := httptest.NewRequest("GET", "/slow-read", nil)
+				req.AddCookie(cookie)
+				r.ServeHTTP(httptest.NewRecorder(), req)
+			}()
+			<-loaded
+			// saved while the read-only request holds visits=1
+			do(t, r, "/visit", cookie)
+			close(release)
+			<-done
+
+			if body, _ := do(t, r, "/visit", cookie); body != "3" {
+				t.Errorf("got %q, want 3: the read-only request overwrote the save", body)
+			}
+		})
+	}
+}
+
+func TestUnsavedChangesDiscarded(t *testing.T) {
+	r := newSessionEngine(NewMemoryStore(Options{}))
+	r.GET("/flash", func(c *gee.Context) {
+		s := Default(c)
+		s.AddFlash("hi")
+		s.Save()
+	})
+	r.GET("/peek", func(c *gee.Context) {
+		s := Default(c)
+		s.Set("visits", 100)
+		c.String(http.StatusOK, "%d", len(s.Flashes()))
+	})
+	_, cookie := do(t, r, "/visit", nil)
+	do(t, r, "/flash", cookie)
+	for i := 0; i < 2; i++ {
+		if body, _ := do(t, r, "/peek", cookie); body != "1" {
+			t.Errorf("peek %d: got %q flashes, want the unsaved removal dropped", i, body)
+		}
+	}
+	if body, _ := do(t, r, "/visit", cookie); body != "2" {
+		t.Errorf("got %q, want the unsaved Set dropped", body)
+	}
+}
+
+func TestReadsRefreshIdleTimeout(t *testing.T) {
+	for name, store := range map[string]Store{
+		"memory": NewMemoryStore(Options{IdleTimeout: 80 * time.Millisecond}),
+		"file":   NewFileStore(t.TempDir(), Options{IdleTimeout: 80 * time.Millisecond}),
+	} {
+		r := newSessionEngine(store)
+		r.GET("/peek", func(c *gee.Context) {
+			c.String(http.StatusOK, "%v", Default(c).Get("visits"))
+		})
+		_, cookie := do(t, r, "/visit", nil)
+		for i := 0; i < 4; i++ {
+			time.Sleep(40 * time.Millisecond)
+			if body, _ := do(t, r, "/peek", cookie); body != "1" {
+				t.Fatalf("%s: read %d got %q, want the session kept alive", name, i, body)
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package gee
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader carries the request ID from the client or a proxy and
+// back to the client
+const RequestIDHeader = "X-Request-ID"
+
+// SetLogger sets the structured logger of the engine, Logger then emits
+// slog records through it
+func (engine *Engine) SetLogger(logger *slog.Logger) {
+	engine.logger = logger
+}
+
+// Logger returns the logger of the engine, slog.Default() if none was set
+func (engine *Engine) Logger() *slog.Logger {
+	if engine.logger == nil {
+		return slog.Default()
+	}
+	return engine.logger
+}
+
+// Logger returns the logger of the request, carrying its ID, route pattern
+// and client IP
+func (c *Context) Logger() *slog.Logger {
+	if c.logger == nil {
+		base := slog.Default()
+		if c.engine != nil {
+			base = c.engine.Logger()
+		}
+		c.logger = base.With(
+			slog.String("request_id", c.RequestID()),
+			slog.String("route", c.FullPath()),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+	return c.logger
+}
+
+// RequestID returns the ID of the request taken from the X-Request-ID
+// header, or a generated one. It is assigned when the request starts and
+// echoed in the response.
+func (c *Context) RequestID() string {
+	if c.requestID == "" {
+		c.assignRequestID()
+	}
+	return c.requestID
+}
+
+// assignRequestID sets the ID and its response header, which must happen
+// before anything is written
+func (c *Context) assignRequestID() {
+	id := c.Req.Header.Get(RequestIDHeader)
+	if !validRequestID(id) {
+		b := make([]byte, 16)
+		rand.Read(b)
+		id = hex.EncodeToString(b)
+	}
+	c.requestID = id
+	c.SetHeader(RequestIDHeader, id)
+}
+
+// validRequestID keeps IDs short and printable so they are safe to log
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7E {
+			return false
+		}
+	}
+	return true
+}
+
+// FullPath returns the pattern of the matched route, e.g. "/user/:id",
+// or "" when no route matched
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
+// logRecord emits the access log record of a finished request, 5xx at
+// error level and 4xx at warn level
+func logRecord(c *Context, p LogParams) {
+	level := slog.LevelInfo
+	switch {
+	case p.StatusCode >= http.StatusInternalServerError:
+		level = slog.LevelError
+	case p.StatusCode >= http.StatusBadRequest:
+		level = slog.LevelWarn
+	}
+	c.Logger().LogAttrs(c.Req.Context(), level, "request",
+		slog.String("method", p.Method),
+		slog.String("path", p.Path),
+		slog.Int("status", p.StatusCode),
+		slog.Duration("latency", p.Latency.Truncate(time.Microsecond)),
+		slog.Int("size", p.BodySize),
+	)
+}
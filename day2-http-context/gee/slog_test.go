@@ -0,0 +1,48 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestIDEchoed(t *testing.T) {
+	r := New()
+	var seen string
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "hello")
+		// asked for only after the response was written
+		seen = c.RequestID()
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	id := w.Header().Get(RequestIDHeader)
+	if len(id) != 32 || id != seen {
+		t.Errorf("generated ID: header %q, handler saw %q", id, seen)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "from-proxy-1")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get(RequestIDHeader); got != "from-proxy-1" || seen != got {
+		t.Errorf("incoming ID: header %q, handler saw %q", got, seen)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "bad id\x01")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get(RequestIDHeader); len(got) != 32 {
+		t.Errorf("invalid incoming ID was not replaced: %q", got)
+	}
+}
+
+func TestRequestIDNotFound(t *testing.T) {
+	w := httptest.NewRecorder()
+	New().ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	if w.Code != http.StatusNotFound || w.Header().Get(RequestIDHeader) == "" {
+		t.Errorf("got %d with ID %q", w.Code, w.Header().Get(RequestIDHeader))
+	}
+}
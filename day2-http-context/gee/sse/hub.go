@@ -0,0 +1,270 @@
+// Package sse fans Server-Sent Events out to many clients. A Hub keeps one
+// bounded queue per client so a slow browser never blocks the publisher.
+//
+//	hub := sse.NewHub(sse.Config{Replay: 50})
+//	r.GET("/events", hub.Handler("dashboard"))
+//	hub.Publish("dashboard", "stats", stats)
+package sse
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"gee"
+	"gee/render"
+)
+
+// Event is the frame delivered to clients, its ID is assigned by the Hub
+type Event = render.SSEvent
+
+// Policy decides what happens to a client whose queue is full
+type Policy int
+
+const (
+	// Drop discards the event for that client only. The client is not
+	// told and the event is lost for it: the stream goes on with later
+	// events, so even a reconnect resumes after them, not from the gap.
+	Drop Policy = iota
+	// Disconnect closes the stream of that client. It reconnects with the
+	// ID of the last event it got and, with Replay set, catches up on the
+	// events it missed that are still kept.
+	Disconnect
+)
+
+// Config tunes a Hub, zero values pick the defaults
+type Config struct {
+	// BufferSize is the number of queued events per client, 64 by default
+	BufferSize int
+	// SlowClient is applied when a client queue is full, Drop by default
+	SlowClient Policy
+	// Heartbeat is the interval of keep-alive comments, 15s by default,
+	// a negative value turns them off
+	Heartbeat time.Duration
+	// Replay is the number of recent events kept per topic and re-sent to
+	// clients reconnecting with Last-Event-ID
+	Replay int
+	// Retry is the reconnection delay suggested to browsers
+	Retry time.Duration
+}
+
+// Hub fans events out to the clients subscribed to their topic
+type Hub struct {
+	config Config
+
+	mu      sync.Mutex
+	seq     uint64
+	topics  map[string]*topic
+	clients map[*client]struct{}
+	closed  bool
+}
+
+// broadcastTopic is implicitly subscribed by every client
+const broadcastTopic = ""
+
+type topic struct {
+	clients map[*client]struct{}
+	history []Event
+}
+
+type client struct {
+	topics []string
+	events chan Event
+}
+
+// NewHub is the constructor of sse.Hub
+func NewHub(config Config) *Hub {
+	if config.BufferSize <= 0 {
+		config.BufferSize = 64
+	}
+	if config.Heartbeat == 0 {
+		config.Heartbeat = 15 * time.Second
+	}
+	return &Hub{
+		config:  config,
+		topics:  make(map[string]*topic),
+		clients: make(map[*client]struct{}),
+	}
+}
+
+// Publish sends an event named event to the subscribers of name and
+// returns its ID
+func (h *Hub) Publish(name string, event string, data interface{}) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return ""
+	}
+	h.seq++
+	ev := Event{ID: strconv.FormatUint(h.seq, 10), Event: event, Data: data}
+	t := h.topic(name)
+	if h.config.Replay > 0 {
+		t.history = append(t.history, ev)
+		if len(t.history) > h.config.Replay {
+			t.history = t.history[len(t.history)-h.config.Replay:]
+		}
+	}
+	for cl := range t.clients {
+		select {
+		case cl.events <- ev:
+		default:
+			if h.config.SlowClient == Disconnect {
+				h.remove(cl)
+			}
+		}
+	}
+	if len(t.clients) == 0 && len(t.history) == 0 {
+		delete(h.topics, name)
+	}
+	return ev.ID
+}
+
+// Broadcast sends an event named event to every connected client
+func (h *Hub) Broadcast(event string, data interface{}) string {
+	return h.Publish(broadcastTopic, event, data)
+}
+
+// Clients returns the number of connected clients
+func (h *Hub) Clients() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// Close ends every stream, the Hub drops later events and subscribers
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for cl := range h.clients {
+		h.remove(cl)
+	}
+}
+
+// Handler returns a gee.HandlerFunc streaming the given topics, or the
+// topics listed in the "topic" query parameter when none are given
+func (h *Hub) Handler(topics ...string) gee.HandlerFunc {
+	return func(c *gee.Context) {
+		names := topics
+		if len(names) == 0 {
+			names = c.Req.URL.Query()["topic"]
+		}
+		h.Serve(c, names...)
+	}
+}
+
+// Serve streams the topics to c until the client leaves, it is dropped
+// by the SlowClient policy or the Hub is closed
+func (h *Hub) Serve(c *gee.Context, topics ...string) {
+	cl, replay, ok := h.subscribe(topics, c.LastEventID())
+	if !ok {
+		c.String(http.StatusServiceUnavailable, "503 SERVICE UNAVAILABLE\n")
+		return
+	}
+	defer h.unsubscribe(cl)
+
+	hello := Event{Comment: "connected"}
+	if h.config.Retry > 0 {
+		hello.Retry = uint(h.config.Retry / time.Millisecond)
+	}
+	c.RenderSSE(hello)
+	for _, ev := range replay {
+		c.RenderSSE(ev)
+	}
+
+	var heartbeat <-chan time.Time
+	if h.config.Heartbeat > 0 {
+		ticker := time.NewTicker(h.config.Heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	done := c.Req.Context().Done()
+	for {
+		select {
+		case ev, ok := <-cl.events:
+			if !ok {
+				return
+			}
+			c.RenderSSE(ev)
+		case <-heartbeat:
+			c.RenderSSE(Event{Comment: "heartbeat"})
+		case <-done:
+			return
+		}
+	}
+}
+
+// subscribe registers a client and collects the events it missed since lastID
+func (h *Hub) subscribe(topics []string, lastID string) (*client, []Event, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, nil, false
+	}
+	cl := &client{
+		topics: []string{broadcastTopic},
+		events: make(chan Event, h.config.BufferSize),
+	}
+	for _, name := range topics {
+		if !slices.Contains(cl.topics, name) {
+			cl.topics = append(cl.topics, name)
+		}
+	}
+	h.clients[cl] = struct{}{}
+	for _, name := range cl.topics {
+		h.topic(name).clients[cl] = struct{}{}
+	}
+
+	var replay []Event
+	if last, err := strconv.ParseUint(lastID, 10, 64); err == nil {
+		for _, name := range cl.topics {
+			for _, ev := range h.topics[name].history {
+				if id, _ := strconv.ParseUint(ev.ID, 10, 64); id > last {
+					replay = append(replay, ev)
+				}
+			}
+		}
+		sort.Slice(replay, func(i, j int) bool {
+			a, _ := strconv.ParseUint(replay[i].ID, 10, 64)
+			b, _ := strconv.ParseUint(replay[j].ID, 10, 64)
+			return a < b
+		})
+	}
+	return cl, replay, true
+}
+
+func (h *Hub) unsubscribe(cl *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(cl)
+}
+
+// remove detaches cl and closes its queue, h.mu must be held
+func (h *Hub) remove(cl *client) {
+	if _, ok := h.clients[cl]; !ok {
+		return
+	}
+	delete(h.clients, cl)
+	for _, name := range cl.topics {
+		if t, ok := h.topics[name]; ok {
+			delete(t.clients, cl)
+			if len(t.clients) == 0 && len(t.history) == 0 {
+				delete(h.topics, name)
+			}
+		}
+	}
+	close(cl.events)
+}
+
+// topic returns the topic called name, creating it when needed
+func (h *Hub) topic(name string) *topic {
+	t, ok := h.topics[name]
+	if !ok {
+		t = &topic{clients: make(map[*client]struct{})}
+		h.topics[name] = t
+	}
+	return t
+}
@@ -0,0 +1,161 @@
+package sse
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gee"
+)
+
+func init() {
+	gee.SetMode(gee.TestMode)
+}
+
+// stream connects to the hub handler and returns a reader of its frames
+func stream(t *testing.T, url string, lastID string) (*bufio.Reader, io.Closer) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", url, nil)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d", resp.StatusCode)
+	}
+	return bufio.NewReader(resp.Body), resp.Body
+}
+
+// readFrame returns the lines of the next frame joined with "|"
+func readFrame(t *testing.T, br *bufio.Reader) string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read frame: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return strings.Join(lines, "|")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func waitClients(t *testing.T, hub *Hub, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Clients() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d clients, want %d", hub.Clients(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSlowClientDrop(t *testing.T) {
+	hub := NewHub(Config{BufferSize: 2, SlowClient: Drop})
+	cl, _, _ := hub.subscribe([]string{"a"}, "")
+	for i := 0; i < 5; i++ {
+		hub.Publish("a", "tick", i)
+	}
+	if hub.Clients() != 1 {
+		t.Fatalf("slow client was removed with the Drop policy")
+	}
+	if len(cl.events) != 2 {
+		t.Fatalf("got %d queued events, want 2", len(cl.events))
+	}
+	if ev := <-cl.events; ev.ID != "1" {
+		t.Errorf("got event %s first, want the oldest one", ev.ID)
+	}
+}
+
+func TestSlowClientDisconnect(t *testing.T) {
+	hub := NewHub(Config{BufferSize: 1, SlowClient: Disconnect})
+	slow, _, _ := hub.subscribe([]string{"a"}, "")
+	other, _, _ := hub.subscribe([]string{"b"}, "")
+	hub.Publish("a", "tick", 1)
+	hub.Publish("a", "tick", 2)
+	if hub.Clients() != 1 {
+		t.Fatalf("got %d clients, want the slow one removed", hub.Clients())
+	}
+	<-slow.events
+	if _, ok := <-slow.events; ok {
+		t.Errorf("queue of the removed client is still open")
+	}
+	hub.Publish("b", "tick", 3)
+	if ev := <-other.events; ev.ID != "3" {
+		t.Errorf("other client got %q, want event 3", ev.ID)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	hub := NewHub(Config{Replay: 10, Heartbeat: -1})
+	defer hub.Close()
+	r := gee.New()
+	r.GET("/events", hub.Handler("a"))
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	hub.Publish("a", "tick", "1")
+	hub.Publish("b", "tick", "2")
+	hub.Publish("a", "tick", "3")
+	hub.Broadcast("news", "4")
+
+	br, body := stream(t, ts.URL+"/events", "1")
+	defer body.Close()
+	want := []string{
+		": connected",
+		"id: 3|event: tick|data: 3",
+		"id: 4|event: news|data: 4",
+	}
+	for _, w := range want {
+		if got := readFrame(t, br); got != w {
+			t.Errorf("got frame %q, want %q", got, w)
+		}
+	}
+	hub.Publish("a", "tick", "5")
+	if got := readFrame(t, br); got != "id: 5|event: tick|data: 5" {
+		t.Errorf("got live frame %q", got)
+	}
+}
+
+func TestCloseEndsStreams(t *testing.T) {
+	hub := NewHub(Config{Heartbeat: -1})
+	r := gee.New()
+	r.GET("/events", hub.Handler("a"))
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	br, body := stream(t, ts.URL+"/events", "")
+	defer body.Close()
+	readFrame(t, br)
+	waitClients(t, hub, 1)
+
+	hub.Close()
+	if _, err := io.ReadAll(br); err != nil {
+		t.Fatalf("stream did not end cleanly: %v", err)
+	}
+	if hub.Clients() != 0 {
+		t.Errorf("got %d clients after Close", hub.Clients())
+	}
+	if id := hub.Publish("a", "tick", 1); id != "" {
+		t.Errorf("Publish after Close returned %q", id)
+	}
+	resp, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got %d for a new client after Close, want 503", resp.StatusCode)
+	}
+}
@@ -0,0 +1,280 @@
+package gee
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gee/render"
+)
+
+// listingFS marks a file system whose directories may be listed
+type listingFS struct {
+	fs.FS
+}
+
+// ListDirectory wraps fsys so that directories without an index.html are listed
+func ListDirectory(fsys fs.FS) fs.FS {
+	return listingFS{fsys}
+}
+
+// Dir returns the file system of the directory root, with directory listing
+// turned on when listDirectory is set
+func Dir(root string, listDirectory bool) fs.FS {
+	fsys := os.DirFS(root)
+	if listDirectory {
+		return ListDirectory(fsys)
+	}
+	return fsys
+}
+
+// Static serves the files under the local directory root at prefix,
+// e.g. Static("/assets", "./static") serves ./static/js/app.js at /assets/js/app.js
+func (engine *Engine) Static(prefix string, root string) {
+	engine.StaticFS(prefix, Dir(root, false))
+}
+
+// StaticFS serves the files of fsys at prefix. Use fs.Sub to serve a
+// sub directory of an embed.FS, and ListDirectory to allow listings.
+func (engine *Engine) StaticFS(prefix string, fsys fs.FS) {
+	if strings.ContainsAny(prefix, ":*") {
+		panic("gee: URL parameters can not be used when serving a static folder")
+	}
+	tags := new(sync.Map)
+	handler := func(c *Context) {
+		serveFS(c, fsys, tags, c.Param("filepath"))
+	}
+	for _, pattern := range []string{prefix + "/", path.Join(prefix, "/*filepath")} {
+		engine.addRoute("GET", pattern, handler)
+		engine.addRoute("HEAD", pattern, handler)
+	}
+}
+
+// StaticFile serves the single local file at relativePath
+func (engine *Engine) StaticFile(relativePath string, file string) {
+	if strings.ContainsAny(relativePath, ":*") {
+		panic("gee: URL parameters can not be used when serving a static file")
+	}
+	dir, name := filepath.Split(file)
+	fsys := os.DirFS(dir)
+	tags := new(sync.Map)
+	handler := func(c *Context) {
+		serveFS(c, fsys, tags, name)
+	}
+	engine.addRoute("GET", relativePath, handler)
+	engine.addRoute("HEAD", relativePath, handler)
+}
+
+// serveFS answers the request with the file name of fsys. Range,
+// If-Modified-Since and friends are handled by http.ServeContent, tags
+// caches the entity tags of fsys, see fileETag.
+func serveFS(c *Context, fsys fs.FS, tags *sync.Map, name string) {
+	name, ok := cleanFilePath(name)
+	if !ok {
+		c.String(http.StatusBadRequest, "400 BAD REQUEST: %s\n", c.Path)
+		return
+	}
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		notFound(c)
+		return
+	}
+	if info.IsDir() {
+		if !strings.HasSuffix(c.Req.URL.Path, "/") {
+			localRedirect(c, path.Base(c.Req.URL.Path)+"/")
+			return
+		}
+		index := path.Join(name, "index.html")
+		if info, err := fs.Stat(fsys, index); err == nil && !info.IsDir() {
+			serveFile(c, fsys, tags, index)
+			return
+		}
+		if _, ok := fsys.(listingFS); ok {
+			dirList(c, fsys, name)
+			return
+		}
+		notFound(c)
+		return
+	}
+	serveFile(c, fsys, tags, name)
+}
+
+// cleanFilePath turns a request path into an fs.FS name, rejecting
+// any attempt to climb out of the root
+func cleanFilePath(name string) (string, bool) {
+	if strings.ContainsAny(name, "\\\x00") {
+		return "", false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", false
+		}
+	}
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+	return name, fs.ValidPath(name)
+}
+
+// serveFile writes the file name, or its precompressed name.gz sibling when
+// the client accepts gzip
+func serveFile(c *Context, fsys fs.FS, tags *sync.Map, name string) {
+	served := name
+	if info, err := fs.Stat(fsys, name+".gz"); err == nil && !info.IsDir() {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		if acceptsEncoding(c.Req.Header.Get("Accept-Encoding"), "gzip") {
+			served = name + ".gz"
+			c.SetHeader("Content-Encoding", "gzip")
+		}
+	}
+	f, err := fsys.Open(served)
+	if err != nil {
+		notFound(c)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "500 INTERNAL SERVER ERROR\n")
+		return
+	}
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			c.String(http.StatusInternalServerError, "500 INTERNAL SERVER ERROR\n")
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+	if c.Writer.Header().Get("ETag") == "" {
+		tag, err := fileETag(tags, served, info, content)
+		if err != nil {
+			c.String(http.StatusInternalServerError, "500 INTERNAL SERVER ERROR\n")
+			return
+		}
+		if served != name {
+			tag += "-gz"
+		}
+		c.SetHeader("ETag", `"`+tag+`"`)
+	}
+	// the content type comes from the original name, not from .gz
+	http.ServeContent(c.Writer, c.Req, name, info.ModTime(), content)
+}
+
+// fileETag derives the entity tag of a file from its size and modification
+// time, or from a hash of its content when it has no modification time, as
+// is the case for embed.FS files. Such content never changes, so the hash is
+// kept in tags by name and computed only once.
+func fileETag(tags *sync.Map, name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if modtime := info.ModTime(); !modtime.IsZero() {
+		return fmt.Sprintf("%x-%x", info.Size(), modtime.UnixNano()), nil
+	}
+	if tag, ok := tags.Load(name); ok {
+		return tag.(string), nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	tag := hex.EncodeToString(h.Sum(nil)[:16])
+	tags.Store(name, tag)
+	return tag, nil
+}
+
+func localRedirect(c *Context, target string) {
+	if q := c.Req.URL.RawQuery; q != "" {
+		target += "?" + q
+	}
+	c.SetHeader("Location", target)
+	c.Status(http.StatusMovedPermanently)
+}
+
+func dirList(c *Context, fsys fs.FS, name string) {
+	entries, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "500 INTERNAL SERVER ERROR\n")
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString("<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n")
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+		link := url.URL{Path: entryName}
+		fmt.Fprintf(&buf, "<a href=\"%s\">%s</a>\n", link.String(), html.EscapeString(entryName))
+	}
+	buf.WriteString("</pre>\n")
+	c.Render(http.StatusOK, render.Data{ContentType: "text/html; charset=utf-8", Data: buf.Bytes()})
+}
+
+// StaticSPA serves a single page application from fsys at prefix. Existing
+// files are served as usual, HTML navigations to unknown paths get index so
+// client-side routing works, while missing assets and SPAExcludes paths
+// such as /api/ still 404. It takes over the NoRoute handler.
+func (engine *Engine) StaticSPA(prefix string, fsys fs.FS, index string) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	tags := new(sync.Map)
+	engine.NoRoute(func(c *Context) {
+		if c.Method != http.MethodGet && c.Method != http.MethodHead {
+			notFound(c)
+			return
+		}
+		rel, ok := strings.CutPrefix(c.Path, prefix)
+		if !ok || (rel != "" && rel[0] != '/' && prefix != "/") {
+			notFound(c)
+			return
+		}
+		for _, exclude := range engine.SPAExcludes {
+			if strings.HasPrefix(c.Path, exclude) || c.Path+"/" == exclude {
+				notFound(c)
+				return
+			}
+		}
+		name, ok := cleanFilePath(rel)
+		if !ok {
+			c.String(http.StatusBadRequest, "400 BAD REQUEST: %s\n", c.Path)
+			return
+		}
+		if info, err := fs.Stat(fsys, name); err == nil && !info.IsDir() {
+			serveFile(c, fsys, tags, name)
+			return
+		}
+		if (name != "." && path.Ext(name) != "") || !acceptsHTML(c.Req) {
+			notFound(c)
+			return
+		}
+		serveFile(c, fsys, tags, index)
+	})
+}
+
+// acceptsHTML reports whether the client explicitly asks for HTML, as
+// browsers do for navigations but not for scripts, images or fetch calls
+func acceptsHTML(req *http.Request) bool {
+	for _, spec := range parseAccept(req.Header.Get("Accept")) {
+		if spec.q > 0 && spec.typ == "text" && spec.subtype == "html" {
+			return true
+		}
+		if spec.q > 0 && spec.typ == "application" && spec.subtype == "xhtml+xml" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,315 @@
+package gee
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestStaticETag(t *testing.T) {
+	r := New()
+	r.StaticFS("/embed", fstest.MapFS{"app.js": {Data: []byte("console.log(1)")}})
+	r.StaticFS("/disk", fstest.MapFS{"app.js": {Data: []byte("console.log(2)"), ModTime: time.Unix(1700000000, 0)}})
+	for _, path := range []string{"/embed/app.js", "/disk/app.js"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		etag := w.Header().Get("ETag")
+		if w.Code != http.StatusOK || etag == "" {
+			t.Fatalf("%s: got %d with ETag %q", path, w.Code, etag)
+		}
+
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("%s: If-None-Match got %d %q, want 304", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestStaticETagDiffers(t *testing.T) {
+	r := New()
+	r.StaticFS("/a", fstest.MapFS{"f": {Data: []byte("one")}})
+	r.StaticFS("/b", fstest.MapFS{"f": {Data: []byte("two")}})
+	tags := map[string]bool{}
+	for _, path := range []string{"/a/f", "/b/f"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		tags[w.Header().Get("ETag")] = true
+	}
+	if len(tags) != 2 {
+		t.Errorf("files with different content share an ETag: %v", tags)
+	}
+}
+
+func TestCleanFilePath(t *testing.T) {
+	tests := []struct {
+		in, out string
+		ok      bool
+	}{
+		{"", ".", true},
+		{"/", ".", true},
+		{"/css/app.css", "css/app.css", true},
+		{"css//app.css", "css/app.css", true},
+		{"./css/./app.css", "css/app.css", true},
+		{"/css/", "css", true},
+		{"..", "", false},
+		{"/../secret", "", false},
+		{"css/../../secret", "", false},
+		{"css/../app.css", "", false},
+		{`..\secret`, "", false},
+		{"app.css\x00.png", "", false},
+		{"..foo/bar..", "..foo/bar..", true},
+	}
+	for _, tt := range tests {
+		out, ok := cleanFilePath(tt.in)
+		if out != tt.out || ok != tt.ok {
+			t.Errorf("cleanFilePath(%q) = %q, %v, want %q, %v", tt.in, out, ok, tt.out, tt.ok)
+		}
+	}
+}
+
+func staticEngine(fsys fs.FS) *Engine {
+	r := New()
+	r.StaticFS("/files", fsys)
+	return r
+}
+
+func get(r *Engine, path string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestStaticTraversal(t *testing.T) {
+	r := staticEngine(fstest.MapFS{"public.txt": {Data: []byte("public")}})
+	for _, path := range []string{`/files/..%5csecret`, "/files/a%00b"} {
+		if w := get(r, path, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", path, w.Code)
+		}
+	}
+	if w := get(r, "/files/public.txt", nil); w.Body.String() != "public" {
+		t.Errorf("got %q", w.Body.String())
+	}
+}
+
+func TestStaticRange(t *testing.T) {
+	r := staticEngine(fstest.MapFS{"a.txt": {Data: []byte("0123456789")}})
+	w := get(r, "/files/a.txt", map[string]string{"Range": "bytes=2-5"})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "2345" {
+		t.Errorf("got %d %q, want 206 2345", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Range"); got != "bytes 2-5/10" {
+		t.Errorf("Content-Range = %q", got)
+	}
+	w = get(r, "/files/a.txt", map[string]string{"Range": "bytes=20-"})
+	if w.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("unsatisfiable range got %d, want 416", w.Code)
+	}
+}
+
+func TestStaticGzipSibling(t *testing.T) {
+	r := staticEngine(fstest.MapFS{
+		"app.js":    {Data: []byte("plain")},
+		"app.js.gz": {Data: []byte("zipped")},
+	})
+	plain := get(r, "/files/app.js", nil)
+	zipped := get(r, "/files/app.js", map[string]string{"Accept-Encoding": "br, gzip"})
+	if plain.Body.String() != "plain" || plain.Header().Get("Content-Encoding") != "" {
+		t.Errorf("without gzip got %q encoded %q", plain.Body.String(), plain.Header().Get("Content-Encoding"))
+	}
+	if zipped.Body.String() != "zipped" || zipped.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("with gzip got %q encoded %q", zipped.Body.String(), zipped.Header().Get("Content-Encoding"))
+	}
+	for _, w := range []*httptest.ResponseRecorder{plain, zipped} {
+		if w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("Vary = %q", w.Header().Get("Vary"))
+		}
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/javascript") {
+			t.Errorf("Content-Type = %q, want the one of app.js", w.Header().Get("Content-Type"))
+		}
+	}
+	if plain.Header().Get("ETag") == zipped.Header().Get("ETag") {
+		t.Errorf("both encodings share the ETag %q", plain.Header().Get("ETag"))
+	}
+	if w := get(r, "/files/app.js", map[string]string{"Accept-Encoding": "gzip;q=0"}); w.Body.String() != "plain" {
+		t.Errorf("gzip;q=0 got %q", w.Body.String())
+	}
+}
+
+func TestStaticDirectory(t *testing.T) {
+	fsys := fstest.MapFS{
+		"site/index.html": {Data: []byte("<h1>site</h1>")},
+		"docs/a.txt":      {Data: []byte("a")},
+		"docs/b <c>.txt":  {Data: []byte("b")},
+	}
+	r := New()
+	r.StaticFS("/hidden", fsys)
+	r.StaticFS("/listed", ListDirectory(fsys))
+
+	w := get(r, "/hidden/site?v=1", nil)
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "site/?v=1" {
+		t.Errorf("directory without slash got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := get(r, "/hidden/site/", nil); w.Code != http.StatusOK || w.Body.String() != "<h1>site</h1>" {
+		t.Errorf("index.html got %d %q", w.Code, w.Body.String())
+	}
+	if w := get(r, "/hidden/docs/", nil); w.Code != http.StatusNotFound {
+		t.Errorf("listing off got %d, want 404", w.Code)
+	}
+
+	w = get(r, "/listed/docs/", nil)
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(body, `<a href="a.txt">a.txt</a>`) {
+		t.Errorf("listing on got %d %q", w.Code, body)
+	}
+	if !strings.Contains(body, `<a href="b%20%3Cc%3E.txt">b &lt;c&gt;.txt</a>`) {
+		t.Errorf("listing does not escape names: %q", body)
+	}
+	if w := get(r, "/listed/site/", nil); w.Body.String() != "<h1>site</h1>" {
+		t.Errorf("index.html is not preferred over the listing: %q", w.Body.String())
+	}
+}
+
+func TestStaticIfModifiedSince(t *testing.T) {
+	modtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := staticEngine(fstest.MapFS{"a.txt": {Data: []byte("a"), ModTime: modtime}})
+	w := get(r, "/files/a.txt", nil)
+	if got := w.Header().Get("Last-Modified"); got != modtime.Format(http.TimeFormat) {
+		t.Errorf("Last-Modified = %q", got)
+	}
+	tests := map[time.Time]int{
+		modtime:                 http.StatusNotModified,
+		modtime.Add(time.Hour):  http.StatusNotModified,
+		modtime.Add(-time.Hour): http.StatusOK,
+	}
+	for since, code := range tests {
+		w := get(r, "/files/a.txt", map[string]string{"If-Modified-Since": since.Format(http.TimeFormat)})
+		if w.Code != code {
+			t.Errorf("If-Modified-Since %s got %d, want %d", since, w.Code, code)
+		}
+	}
+}
+
+// countingReader counts the reads of the content being hashed
+type countingReader struct {
+	r     *strings.Reader
+	reads int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.reads++
+	return r.r.Read(p)
+}
+
+func (r *countingReader) Seek(offset int64, whence int) (int64, error) {
+	return r.r.Seek(offset, whence)
+}
+
+func TestFileETagCached(t *testing.T) {
+	info, err := fs.Stat(fstest.MapFS{"f": {Data: []byte("one")}}, "f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags := new(sync.Map)
+	content := &countingReader{r: strings.NewReader("one")}
+	first, err := fileETag(tags, "f", info, content)
+	if err != nil || content.reads == 0 {
+		t.Fatalf("got %q %v after %d reads", first, err, content.reads)
+	}
+	content = &countingReader{r: strings.NewReader("one")}
+	second, err := fileETag(tags, "f", info, content)
+	if err != nil || second != first || content.reads != 0 {
+		t.Errorf("second call got %q %v after %d reads, want the cached %q", second, err, content.reads, first)
+	}
+}
+
+func TestStaticSPA(t *testing.T) {
+	r := New()
+	r.GET("/api/users", func(c *Context) {
+		c.String(http.StatusOK, "users")
+	})
+	r.StaticSPA("/app", fstest.MapFS{
+		"index.html":        {Data: []byte("<div id=app>")},
+		"assets/app.js":     {Data: []byte("js")},
+		"assets/app.js.gz":  {Data: []byte("js.gz")},
+		"docs/guide/a.html": {Data: []byte("guide")},
+	}, "index.html")
+	html := map[string]string{"Accept": "text/html,application/xhtml+xml,*/*;q=0.8"}
+	tests := []struct {
+		method, path string
+		header       map[string]string
+		code         int
+		body         string
+	}{
+		{"GET", "/app/assets/app.js", nil, 200, "js"},
+		{"GET", "/app/assets/app.js", map[string]string{"Accept-Encoding": "gzip"}, 200, "js.gz"},
+		{"GET", "/app/docs/guide/a.html", nil, 200, "guide"},
+		{"GET", "/app", html, 200, "<div id=app>"},
+		{"GET", "/app/", html, 200, "<div id=app>"},
+		{"GET", "/app/users/42", html, 200, "<div id=app>"},
+		{"HEAD", "/app/users/42", html, 200, ""},
+		{"GET", "/app/settings", map[string]string{"Accept": "application/xhtml+xml"}, 200, "<div id=app>"},
+
+		// missing assets and non navigations are real 404s
+		{"GET", "/app/assets/missing.js", html, 404, ""},
+		{"GET", "/app/logo.png", html, 404, ""},
+		{"GET", "/app/users/42", map[string]string{"Accept": "application/json"}, 404, ""},
+		{"GET", "/app/users/42", map[string]string{"Accept": "*/*"}, 404, ""},
+		{"GET", "/app/users/42", map[string]string{"Accept": "text/html;q=0"}, 404, ""},
+		{"GET", "/app/users/42", nil, 404, ""},
+		{"POST", "/app/users/42", html, 404, ""},
+
+		// outside the prefix, excluded or escaping the root
+		{"GET", "/application", html, 404, ""},
+		{"GET", "/other", html, 404, ""},
+		{"GET", "/api/missing", html, 404, ""},
+		{"GET", "/api/users", html, 200, "users"},
+		{"GET", "/app/..%5cindex.html", html, 400, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		for key, value := range tt.header {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s %s %v: got %d, want %d", tt.method, tt.path, tt.header, w.Code, tt.code)
+		}
+		if tt.code == 200 && w.Body.String() != tt.body {
+			t.Errorf("%s %s %v: got %q, want %q", tt.method, tt.path, tt.header, w.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestStaticSPARoot(t *testing.T) {
+	r := New()
+	r.SPAExcludes = append(r.SPAExcludes, "/healthz")
+	r.StaticSPA("/", fstest.MapFS{"index.html": {Data: []byte("root")}}, "index.html")
+	html := map[string]string{"Accept": "text/html"}
+	tests := map[string]int{
+		"/":               200,
+		"/deep/link":      200,
+		"/favicon.ico":    404,
+		"/api/v1/users":   404,
+		"/api":            404,
+		"/healthz":        404,
+		"/healthz/status": 404,
+	}
+	for path, code := range tests {
+		if w := get(r, path, html); w.Code != code {
+			t.Errorf("%s: got %d, want %d", path, w.Code, code)
+		}
+	}
+}
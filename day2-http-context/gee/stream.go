@@ -0,0 +1,54 @@
+package gee
+
+import (
+	"io"
+	"net/http"
+
+	"gee/render"
+)
+
+// Stream calls step until it returns false, flushing after every call so
+// the client sees each chunk at once. It returns true when it stopped
+// because the client went away.
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	done := c.Req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+			keepOpen := step(c.Writer)
+			c.Flush()
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
+
+// Flush sends any buffered response data to the client
+func (c *Context) Flush() {
+	_ = http.NewResponseController(c.Writer).Flush()
+}
+
+// SSEvent writes a Server-Sent Event named event and flushes it
+func (c *Context) SSEvent(event string, data interface{}) {
+	c.RenderSSE(render.SSEvent{Event: event, Data: data})
+}
+
+// RenderSSE writes event, including its id and retry fields, and flushes it.
+// The first event starts the response with status 200.
+func (c *Context) RenderSSE(event render.SSEvent) {
+	code := http.StatusOK
+	if c.Written() {
+		code = -1
+	}
+	c.Render(code, event)
+	c.Flush()
+}
+
+// LastEventID returns the id of the last event a reconnecting
+// EventSource received, or "" on the first connection
+func (c *Context) LastEventID() string {
+	return c.Req.Header.Get("Last-Event-ID")
+}
@@ -0,0 +1,141 @@
+package gee
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gee/render"
+)
+
+func TestSSEvent(t *testing.T) {
+	r := New()
+	r.GET("/events", func(c *Context) {
+		next := 1
+		if id, err := strconv.Atoi(c.LastEventID()); err == nil {
+			next = id + 1
+		}
+		c.RenderSSE(render.SSEvent{Retry: 3000})
+		for id := next; id <= 3; id++ {
+			c.RenderSSE(render.SSEvent{
+				ID:    strconv.Itoa(id),
+				Event: "tick",
+				Data:  H{"n": id},
+			})
+		}
+		c.SSEvent("bye", "see\nyou")
+	})
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+"/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+	if cc := resp.Header.Get("Cache-Control"); cc != "no-cache" {
+		t.Fatalf("Cache-Control = %q, want no-cache", cc)
+	}
+
+	var frames []string
+	var frame []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			frame = append(frame, line)
+			continue
+		}
+		frames = append(frames, strings.Join(frame, "|"))
+		frame = nil
+	}
+	want := []string{
+		"retry: 3000",
+		`id: 2|event: tick|data: {"n":2}`,
+		`id: 3|event: tick|data: {"n":3}`,
+		"event: bye|data: see|data: you",
+	}
+	if len(frames) != len(want) {
+		t.Fatalf("got %d frames %q, want %d", len(frames), frames, len(want))
+	}
+	for i := range want {
+		if frames[i] != want[i] {
+			t.Errorf("frame %d = %q, want %q", i, frames[i], want[i])
+		}
+	}
+}
+
+func TestStreamStopsOnDisconnect(t *testing.T) {
+	stopped := make(chan bool, 1)
+	r := New()
+	r.GET("/stream", func(c *Context) {
+		stopped <- c.Stream(func(w io.Writer) bool {
+			io.WriteString(w, "chunk\n")
+			return true
+		})
+	})
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != "chunk\n" {
+		t.Fatalf("first chunk = %q, %v", line, err)
+	}
+	resp.Body.Close()
+
+	if clientGone := <-stopped; !clientGone {
+		t.Fatal("Stream returned false, want true after the client disconnected")
+	}
+}
+
+// headerCounter counts the WriteHeader calls that reach the client
+type headerCounter struct {
+	http.ResponseWriter
+	calls int
+}
+
+func (w *headerCounter) WriteHeader(code int) {
+	w.calls++
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *headerCounter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestRenderSSEAfterDirectWrite(t *testing.T) {
+	r := New()
+	var counter *headerCounter
+	r.Use(func(c *Context) {
+		counter = &headerCounter{ResponseWriter: c.Writer}
+		c.Writer = counter
+		c.Next()
+	})
+	r.GET("/events", func(c *Context) {
+		c.SetHeader("Content-Type", "text/event-stream")
+		// padding written by hand starts the response without c.Status
+		c.Writer.Write([]byte(": padding\n\n"))
+		c.SSEvent("tick", 1)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+	if counter.calls != 0 {
+		t.Errorf("WriteHeader called %d times after the response was started", counter.calls)
+	}
+	if w.Code != http.StatusOK || !strings.HasSuffix(w.Body.String(), "event: tick\ndata: 1\n\n") {
+		t.Errorf("got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,82 @@
+package gee
+
+import "strings"
+
+type node struct {
+	pattern  string  // route to be matched, e.g. /p/:lang, only set on leaves
+	part     string  // part of the route, e.g. :lang
+	children []*node // child nodes
+	isWild   bool    // true when part starts with : or *
+}
+
+// matchChild returns the child whose part is exactly part, used by insert
+func (n *node) matchChild(part string) *node {
+	for _, child := range n.children {
+		if child.part == part {
+			return child
+		}
+	}
+	return nil
+}
+
+// matchChildren returns every child matching part, used by search. Static
+// children come first, then parameters, then catch-alls, so that /p/book
+// wins over /p/:lang which wins over /p/*path.
+func (n *node) matchChildren(part string) []*node {
+	nodes := make([]*node, 0)
+	for _, child := range n.children {
+		if child.part == part && !child.isWild {
+			nodes = append(nodes, child)
+		}
+	}
+	for _, prefix := range []byte{':', '*'} {
+		for _, child := range n.children {
+			if child.isWild && child.part[0] == prefix {
+				nodes = append(nodes, child)
+			}
+		}
+	}
+	return nodes
+}
+
+func (n *node) insert(pattern string, parts []string, height int) {
+	if len(parts) == height {
+		n.pattern = pattern
+		return
+	}
+
+	part := parts[height]
+	child := n.matchChild(part)
+	if child == nil {
+		child = &node{part: part, isWild: part[0] == ':' || part[0] == '*'}
+		if child.isWild {
+			for _, sibling := range n.children {
+				if sibling.isWild && sibling.part[0] == part[0] {
+					panic("gee: " + part + " in " + pattern + " conflicts with " + sibling.part + " of an existing route")
+				}
+			}
+		}
+		n.children = append(n.children, child)
+	}
+	child.insert(pattern, parts, height+1)
+}
+
+func (n *node) search(parts []string, height int) *node {
+	if len(parts) == height || strings.HasPrefix(n.part, "*") {
+		if n.pattern == "" {
+			return nil
+		}
+		return n
+	}
+
+	part := parts[height]
+	children := n.matchChildren(part)
+
+	for _, child := range children {
+		result := child.search(parts, height+1)
+		if result != nil {
+			return result
+		}
+	}
+	return nil
+}
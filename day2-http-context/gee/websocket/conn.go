@@ -0,0 +1,474 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Message types, the values are the opcodes of RFC 6455
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// Close codes of RFC 6455 section 7.4.1
+const (
+	CloseNormalClosure       = 1000
+	CloseGoingAway           = 1001
+	CloseProtocolError       = 1002
+	CloseUnsupportedData     = 1003
+	CloseNoStatusReceived    = 1005
+	CloseAbnormalClosure     = 1006
+	CloseInvalidFramePayload = 1007
+	ClosePolicyViolation     = 1008
+	CloseMessageTooBig       = 1009
+	CloseInternalServerErr   = 1011
+)
+
+const (
+	defaultReadLimit  = 32 << 20
+	maxControlPayload = 125
+	// compressThreshold skips compressing messages too small to benefit
+	compressThreshold = 64
+)
+
+var (
+	// ErrCloseSent is returned when writing after a close frame went out
+	ErrCloseSent = errors.New("websocket: close sent")
+	// ErrReadLimit is returned when a message exceeds the read limit
+	ErrReadLimit = errors.New("websocket: read limit exceeded")
+)
+
+// CloseError is returned by ReadMessage once the peer closed the connection
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return "websocket: close " + strconv.Itoa(e.Code) + " " + e.Text
+}
+
+// IsCloseError reports whether err is a CloseError with one of codes
+func IsCloseError(err error, codes ...int) bool {
+	var ce *CloseError
+	if !errors.As(err, &ce) {
+		return false
+	}
+	for _, code := range codes {
+		if ce.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Conn is a server side WebSocket connection. One goroutine may read
+// while others write, writes are serialized internally.
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	subprotocol string
+	compress    bool
+
+	writeMu       sync.Mutex
+	writeDeadline time.Time
+	closeSent     bool
+	writeCompress bool
+
+	readLimit   int64
+	readErr     error
+	pingHandler func(appData string) error
+	pongHandler func(appData string) error
+	closeOnce   sync.Once
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, compress bool) *Conn {
+	c := &Conn{
+		conn:          conn,
+		br:            br,
+		compress:      compress,
+		writeCompress: compress,
+		readLimit:     defaultReadLimit,
+	}
+	c.pingHandler = func(appData string) error {
+		err := c.WriteControl(PongMessage, []byte(appData), time.Now().Add(time.Second))
+		if errors.Is(err, ErrCloseSent) {
+			return nil
+		}
+		return err
+	}
+	c.pongHandler = func(string) error { return nil }
+	return c
+}
+
+// Subprotocol returns the negotiated protocol, if any
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// RemoteAddr returns the network address of the peer
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadLimit sets the maximum size of a message, after decompression.
+// A limit <= 0 restores the default of 32 MiB.
+func (c *Conn) SetReadLimit(limit int64) {
+	if limit <= 0 {
+		limit = defaultReadLimit
+	}
+	c.readLimit = limit
+}
+
+// SetReadDeadline sets the deadline for future reads, zero means none
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for future writes, zero means none
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.writeDeadline = t
+	return c.conn.SetWriteDeadline(t)
+}
+
+// EnableWriteCompression toggles compression of outgoing messages when
+// permessage-deflate was negotiated
+func (c *Conn) EnableWriteCompression(enable bool) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.writeCompress = enable && c.compress
+}
+
+// SetPingHandler replaces the default handler, which answers with a pong
+func (c *Conn) SetPingHandler(h func(appData string) error) {
+	c.pingHandler = h
+}
+
+// SetPongHandler sets the handler called for every pong, e.g. to extend
+// the read deadline
+func (c *Conn) SetPongHandler(h func(appData string) error) {
+	c.pongHandler = h
+}
+
+// WriteMessage sends data as a single TextMessage or BinaryMessage frame,
+// control messages are passed on to WriteControl
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+	case CloseMessage, PingMessage, PongMessage:
+		return c.WriteControl(messageType, data, time.Time{})
+	default:
+		return errors.New("websocket: bad message type " + strconv.Itoa(messageType))
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	rsv1 := false
+	if c.writeCompress && len(data) >= compressThreshold {
+		compressed, err := compressMessage(data)
+		if err != nil {
+			return err
+		}
+		data, rsv1 = compressed, true
+	}
+	return c.writeFrame(messageType, rsv1, data)
+}
+
+// WriteJSON sends v encoded as JSON in a TextMessage
+func (c *Conn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, data)
+}
+
+// ReadJSON reads the next message and decodes it as JSON into v
+func (c *Conn) ReadJSON(v interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// WriteControl sends a close, ping or pong frame, deadline bounds this
+// write only
+func (c *Conn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType != CloseMessage && messageType != PingMessage && messageType != PongMessage {
+		return errors.New("websocket: bad control message type " + strconv.Itoa(messageType))
+	}
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: control frame payload exceeds 125 bytes")
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if !deadline.IsZero() {
+		c.conn.SetWriteDeadline(deadline)
+		defer c.conn.SetWriteDeadline(c.writeDeadline)
+	}
+	return c.writeFrame(messageType, false, data)
+}
+
+// writeFrame writes one final, unmasked frame, c.writeMu must be held
+func (c *Conn) writeFrame(opcode int, rsv1 bool, payload []byte) error {
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	header := make([]byte, 2, 10+len(payload))
+	header[0] = 0x80 | byte(opcode)
+	if rsv1 {
+		header[0] |= 0x40
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+	_, err := c.conn.Write(append(header, payload...))
+	return err
+}
+
+// FormatCloseMessage builds the payload of a close frame
+func FormatCloseMessage(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return []byte{}
+	}
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	return append(payload, text...)
+}
+
+// Close sends a normal close frame, unless one was sent already, and
+// closes the underlying connection
+func (c *Conn) Close() error {
+	err := c.WriteControl(CloseMessage, FormatCloseMessage(CloseNormalClosure, ""), time.Now().Add(time.Second))
+	if errors.Is(err, ErrCloseSent) {
+		err = nil
+	}
+	var closeErr error
+	c.closeOnce.Do(func() { closeErr = c.conn.Close() })
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// frame is a decoded frame with its payload already unmasked
+type frame struct {
+	fin     bool
+	rsv1    bool
+	opcode  int
+	payload []byte
+}
+
+// ReadMessage returns the next data message. Ping, pong and close frames
+// received meanwhile are handled on the way; after a close frame it
+// returns a *CloseError. Errors are sticky.
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	messageType, p, err = c.readMessage()
+	if err != nil {
+		c.readErr = err
+	}
+	return messageType, p, err
+}
+
+func (c *Conn) readMessage() (int, []byte, error) {
+	var (
+		messageType int
+		compressed  bool
+		message     []byte
+	)
+	for {
+		f, err := c.readFrame(int64(len(message)))
+		if err != nil {
+			return 0, nil, err
+		}
+		switch f.opcode {
+		case PingMessage:
+			if err := c.pingHandler(string(f.payload)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if err := c.pongHandler(string(f.payload)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.handleClose(f.payload)
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+			if f.rsv1 {
+				return 0, nil, c.fail(CloseProtocolError, "RSV1 set on continuation frame")
+			}
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "data frame inside a fragmented message")
+			}
+			messageType, compressed = f.opcode, f.rsv1
+		}
+		message = append(message, f.payload...)
+		if f.fin {
+			break
+		}
+	}
+	if compressed {
+		inflated, err := decompressMessage(message, c.readLimit)
+		if errors.Is(err, errMessageTooBig) {
+			return 0, nil, c.failWith(CloseMessageTooBig, "message too big", ErrReadLimit)
+		}
+		if err != nil {
+			return 0, nil, c.fail(CloseInvalidFramePayload, "invalid compressed data")
+		}
+		message = inflated
+	}
+	if messageType == TextMessage && !utf8.Valid(message) {
+		return 0, nil, c.fail(CloseInvalidFramePayload, "invalid UTF-8 in text message")
+	}
+	return messageType, message, nil
+}
+
+// readFrame reads and validates one frame, buffered is the size of the
+// fragments of the current message read so far
+func (c *Conn) readFrame(buffered int64) (frame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return frame{}, c.abort(err)
+	}
+	f := frame{
+		fin:    head[0]&0x80 != 0,
+		rsv1:   head[0]&0x40 != 0,
+		opcode: int(head[0] & 0x0f),
+	}
+	masked := head[1]&0x80 != 0
+	length := int64(head[1] & 0x7f)
+
+	if head[0]&0x30 != 0 || (f.rsv1 && !c.compress) {
+		return f, c.fail(CloseProtocolError, "unexpected reserved bits")
+	}
+	switch f.opcode {
+	case continuationFrame, TextMessage, BinaryMessage:
+	case CloseMessage, PingMessage, PongMessage:
+		if !f.fin || length > maxControlPayload || f.rsv1 {
+			return f, c.fail(CloseProtocolError, "invalid control frame")
+		}
+	default:
+		return f, c.fail(CloseProtocolError, "unknown opcode "+strconv.Itoa(f.opcode))
+	}
+	if !masked {
+		return f, c.fail(CloseProtocolError, "client frames must be masked")
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return f, c.abort(err)
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return f, c.abort(err)
+		}
+		if ext[0]&0x80 != 0 {
+			return f, c.fail(CloseProtocolError, "invalid payload length")
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if f.opcode < CloseMessage && buffered+length > c.readLimit {
+		return f, c.failWith(CloseMessageTooBig, "message too big", ErrReadLimit)
+	}
+
+	var key [4]byte
+	if _, err := io.ReadFull(c.br, key[:]); err != nil {
+		return f, c.abort(err)
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return f, c.abort(err)
+	}
+	for i := range f.payload {
+		f.payload[i] ^= key[i&3]
+	}
+	return f, nil
+}
+
+// handleClose answers a close frame and returns the matching CloseError
+func (c *Conn) handleClose(payload []byte) error {
+	code, text := CloseNoStatusReceived, ""
+	if len(payload) == 1 {
+		return c.fail(CloseProtocolError, "invalid close payload")
+	}
+	if len(payload) >= 2 {
+		code = int(binary.BigEndian.Uint16(payload))
+		text = string(payload[2:])
+		if !validCloseCode(code) {
+			return c.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(text) {
+			return c.fail(CloseInvalidFramePayload, "invalid UTF-8 in close reason")
+		}
+	}
+	reply := code
+	if reply == CloseNoStatusReceived {
+		reply = CloseNormalClosure
+	}
+	c.WriteControl(CloseMessage, FormatCloseMessage(reply, ""), time.Now().Add(time.Second))
+	return &CloseError{Code: code, Text: text}
+}
+
+func validCloseCode(code int) bool {
+	switch code {
+	case 1004, CloseNoStatusReceived, CloseAbnormalClosure, 1015:
+		return false
+	}
+	return (code >= 1000 && code <= 1014) || (code >= 3000 && code <= 4999)
+}
+
+// fail closes the connection with code after a protocol violation
+func (c *Conn) fail(code int, text string) error {
+	return c.failWith(code, text, &CloseError{Code: code, Text: text})
+}
+
+func (c *Conn) failWith(code int, text string, err error) error {
+	c.WriteControl(CloseMessage, FormatCloseMessage(code, text), time.Now().Add(time.Second))
+	c.closeOnce.Do(func() { c.conn.Close() })
+	return err
+}
+
+// abort reports a connection dropped without a close frame
+func (c *Conn) abort(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &CloseError{Code: CloseAbnormalClosure, Text: io.ErrUnexpectedEOF.Error()}
+	}
+	return err
+}
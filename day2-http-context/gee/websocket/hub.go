@@ -0,0 +1,456 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"gee"
+)
+
+// Message types handled by the Hub itself, they may be overridden with On
+const (
+	TypeJoin          = "room.join"
+	TypeLeave         = "room.leave"
+	TypePresenceJoin  = "presence.join"
+	TypePresenceLeave = "presence.leave"
+	TypeError         = "error"
+)
+
+// ErrClientClosed is returned when sending to a client that has left
+var ErrClientClosed = errors.New("websocket: client closed")
+
+// Envelope is the JSON frame exchanged with clients, Type selects the
+// handler registered with Hub.On
+type Envelope struct {
+	Type string          `json:"type"`
+	Room string          `json:"room,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// HandlerFunc handles one message received from client
+type HandlerFunc func(client *Client, msg Envelope)
+
+// HubConfig tunes a Hub, zero values pick the defaults
+type HubConfig struct {
+	// Upgrader performs the handshake, the zero Upgrader by default
+	Upgrader *Upgrader
+	// SendQueue is the number of outgoing messages buffered per client,
+	// 256 by default. Clients that fall further behind are disconnected.
+	SendQueue int
+	// PingInterval is how often idle clients are pinged, 30s by default.
+	// A client missing two pings in a row is dropped.
+	PingInterval time.Duration
+	// WriteTimeout bounds every write to a client, 10s by default
+	WriteTimeout time.Duration
+	// ClientID names the client of a request for presence tracking,
+	// random by default
+	ClientID func(c *gee.Context) string
+	// AnnouncePresence sends presence.join and presence.leave to a room
+	// when a client enters or leaves it
+	AnnouncePresence bool
+}
+
+// Hub routes JSON envelopes between the clients of named rooms.
+//
+//	hub := websocket.NewHub(websocket.HubConfig{AnnouncePresence: true})
+//	hub.On("chat.message", func(client *websocket.Client, msg websocket.Envelope) {
+//		hub.Broadcast(msg.Room, "chat.message", msg.Data)
+//	})
+//	r.GET("/ws", hub.Handler())
+//	r.OnShutdown(hub.Shutdown)
+type Hub struct {
+	config HubConfig
+
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+	clients  map[*Client]struct{}
+	rooms    map[string]map[*Client]struct{}
+	closing  bool
+	wg       sync.WaitGroup
+}
+
+// Client is one connection of a Hub
+type Client struct {
+	// ID identifies the client in presence lists
+	ID string
+
+	hub   *Hub
+	conn  *Conn
+	rooms map[string]struct{} // guarded by hub.mu
+
+	mu        sync.Mutex
+	send      chan []byte
+	closed    bool
+	closeCode int
+	closeText string
+}
+
+// NewHub is the constructor of websocket.Hub
+func NewHub(config HubConfig) *Hub {
+	if config.Upgrader == nil {
+		config.Upgrader = &Upgrader{}
+	}
+	if config.SendQueue <= 0 {
+		config.SendQueue = 256
+	}
+	if config.PingInterval <= 0 {
+		config.PingInterval = 30 * time.Second
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 10 * time.Second
+	}
+	if config.ClientID == nil {
+		config.ClientID = randomID
+	}
+	h := &Hub{
+		config:   config,
+		handlers: make(map[string]HandlerFunc),
+		clients:  make(map[*Client]struct{}),
+		rooms:    make(map[string]map[*Client]struct{}),
+	}
+	h.On(TypeJoin, func(client *Client, msg Envelope) { client.Join(msg.Room) })
+	h.On(TypeLeave, func(client *Client, msg Envelope) { client.Leave(msg.Room) })
+	return h
+}
+
+// On registers fn for messages of type msgType
+func (h *Hub) On(msgType string, fn HandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers[msgType] = fn
+}
+
+// Handler upgrades the request and serves the client until it leaves
+func (h *Hub) Handler() gee.HandlerFunc {
+	return func(c *gee.Context) {
+		h.mu.RLock()
+		closing := h.closing
+		h.mu.RUnlock()
+		if closing {
+			c.String(http.StatusServiceUnavailable, "503 SERVICE UNAVAILABLE\n")
+			return
+		}
+		conn, err := h.config.Upgrader.Upgrade(c)
+		if err != nil {
+			return
+		}
+		client := &Client{
+			ID:    h.config.ClientID(c),
+			hub:   h,
+			conn:  conn,
+			rooms: make(map[string]struct{}),
+			send:  make(chan []byte, h.config.SendQueue),
+		}
+		if !h.register(client) {
+			conn.WriteControl(CloseMessage, FormatCloseMessage(CloseGoingAway, "server shutting down"), time.Now().Add(time.Second))
+			conn.Close()
+			return
+		}
+		defer h.wg.Done()
+		writerDone := make(chan struct{})
+		go func() {
+			client.writePump()
+			close(writerDone)
+		}()
+		client.readPump()
+		<-writerDone
+		conn.Close()
+	}
+}
+
+// Join adds client to room
+func (h *Hub) Join(client *Client, room string) {
+	if room == "" {
+		return
+	}
+	h.mu.Lock()
+	_, joined := client.rooms[room]
+	_, registered := h.clients[client]
+	if joined || !registered {
+		h.mu.Unlock()
+		return
+	}
+	announce := h.config.AnnouncePresence && !h.present(room, client.ID)
+	client.rooms[room] = struct{}{}
+	if h.rooms[room] == nil {
+		h.rooms[room] = make(map[*Client]struct{})
+	}
+	h.rooms[room][client] = struct{}{}
+	h.mu.Unlock()
+	if announce {
+		h.Broadcast(room, TypePresenceJoin, map[string]string{"id": client.ID})
+	}
+}
+
+// Leave removes client from room
+func (h *Hub) Leave(client *Client, room string) {
+	h.mu.Lock()
+	if _, ok := client.rooms[room]; !ok {
+		h.mu.Unlock()
+		return
+	}
+	h.leave(client, room)
+	announce := h.config.AnnouncePresence && !h.present(room, client.ID)
+	h.mu.Unlock()
+	if announce {
+		h.Broadcast(room, TypePresenceLeave, map[string]string{"id": client.ID})
+	}
+}
+
+// leave detaches client from room, h.mu must be held
+func (h *Hub) leave(client *Client, room string) {
+	delete(client.rooms, room)
+	delete(h.rooms[room], client)
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+}
+
+// present reports whether a client called id is in room, h.mu must be held
+func (h *Hub) present(room string, id string) bool {
+	for member := range h.rooms[room] {
+		if member.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Presence returns the sorted IDs of the clients in room
+func (h *Hub) Presence(room string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	seen := make(map[string]bool)
+	var ids []string
+	for client := range h.rooms[room] {
+		if !seen[client.ID] {
+			seen[client.ID] = true
+			ids = append(ids, client.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Rooms returns the sorted names of the rooms with at least one client
+func (h *Hub) Rooms() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	rooms := make([]string, 0, len(h.rooms))
+	for room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+// Broadcast sends an envelope of type msgType to every client in room
+func (h *Hub) Broadcast(room string, msgType string, data interface{}) error {
+	payload, err := encodeEnvelope(msgType, room, data)
+	if err != nil {
+		return err
+	}
+	h.mu.RLock()
+	members := make([]*Client, 0, len(h.rooms[room]))
+	for client := range h.rooms[room] {
+		members = append(members, client)
+	}
+	h.mu.RUnlock()
+	for _, client := range members {
+		client.enqueue(payload)
+	}
+	return nil
+}
+
+// Shutdown stops accepting clients and drains the connected ones: queued
+// messages are flushed, then each client gets a going-away close frame.
+// Clients still connected when ctx is done are cut off. Register it with
+// Engine.OnShutdown.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closing = true
+	clients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.Unlock()
+	for _, client := range clients {
+		client.close(CloseGoingAway, "server shutting down")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		for _, client := range clients {
+			client.conn.conn.Close()
+		}
+		return ctx.Err()
+	}
+}
+
+func (h *Hub) register(client *Client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closing {
+		return false
+	}
+	h.clients[client] = struct{}{}
+	h.wg.Add(1)
+	return true
+}
+
+// unregister removes client from the Hub and every room it joined
+func (h *Hub) unregister(client *Client) {
+	h.mu.Lock()
+	delete(h.clients, client)
+	rooms := make([]string, 0, len(client.rooms))
+	for room := range client.rooms {
+		rooms = append(rooms, room)
+	}
+	h.mu.Unlock()
+	for _, room := range rooms {
+		h.Leave(client, room)
+	}
+	client.close(CloseNormalClosure, "")
+}
+
+// Join adds the client to room
+func (c *Client) Join(room string) {
+	c.hub.Join(c, room)
+}
+
+// Leave removes the client from room
+func (c *Client) Leave(room string) {
+	c.hub.Leave(c, room)
+}
+
+// Send queues an envelope of type msgType for the client
+func (c *Client) Send(msgType string, data interface{}) error {
+	payload, err := encodeEnvelope(msgType, "", data)
+	if err != nil {
+		return err
+	}
+	if !c.enqueue(payload) {
+		return ErrClientClosed
+	}
+	return nil
+}
+
+// enqueue adds payload to the send queue, a full queue disconnects the client
+func (c *Client) enqueue(payload []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	select {
+	case c.send <- payload:
+		return true
+	default:
+		c.closeLocked(ClosePolicyViolation, "send queue full")
+		return false
+	}
+}
+
+// close ends the send queue, the writer flushes it and sends a close frame
+func (c *Client) close(code int, text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeLocked(code, text)
+}
+
+func (c *Client) closeLocked(code int, text string) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.closeCode, c.closeText = code, text
+	close(c.send)
+}
+
+func (c *Client) readPump() {
+	defer c.hub.unregister(c)
+	pongWait := 2 * c.hub.config.PingInterval
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		var msg Envelope
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type == "" {
+			c.Send(TypeError, "invalid envelope")
+			continue
+		}
+		c.hub.mu.RLock()
+		handler := c.hub.handlers[msg.Type]
+		c.hub.mu.RUnlock()
+		if handler == nil {
+			c.Send(TypeError, "unknown message type: "+msg.Type)
+			continue
+		}
+		handler(c, msg)
+	}
+}
+
+func (c *Client) writePump() {
+	ticker := time.NewTicker(c.hub.config.PingInterval)
+	defer ticker.Stop()
+	timeout := c.hub.config.WriteTimeout
+	for {
+		select {
+		case payload, ok := <-c.send:
+			if !ok {
+				c.mu.Lock()
+				code, text := c.closeCode, c.closeText
+				c.mu.Unlock()
+				c.conn.WriteControl(CloseMessage, FormatCloseMessage(code, text), time.Now().Add(timeout))
+				return
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(timeout))
+			if err := c.conn.WriteMessage(TextMessage, payload); err != nil {
+				c.conn.conn.Close()
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(PingMessage, nil, time.Now().Add(timeout)); err != nil {
+				c.conn.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func encodeEnvelope(msgType string, room string, data interface{}) ([]byte, error) {
+	var raw json.RawMessage
+	if data != nil {
+		var err error
+		if raw, err = json.Marshal(data); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(Envelope{Type: msgType, Room: room, Data: raw})
+}
+
+func randomID(*gee.Context) string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,162 @@
+package websocket
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"gee"
+)
+
+func newHubServer(t *testing.T, config HubConfig) (*Hub, *httptest.Server) {
+	config.ClientID = func(c *gee.Context) string { return c.Req.Header.Get("X-Client-ID") }
+	hub := NewHub(config)
+	r := gee.New()
+	r.GET("/ws", hub.Handler())
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	return hub, ts
+}
+
+func dialHub(t *testing.T, ts *httptest.Server, id string) *testClient {
+	t.Helper()
+	tc := dial(t, ts, http.Header{"X-Client-Id": {id}})
+	if tc.resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("got status %d", tc.resp.StatusCode)
+	}
+	return tc
+}
+
+func (tc *testClient) send(msg Envelope) {
+	tc.t.Helper()
+	data, _ := json.Marshal(msg)
+	tc.writeFrame(true, false, TextMessage, data, false)
+}
+
+func (tc *testClient) expectEnvelope(msgType string) Envelope {
+	tc.t.Helper()
+	opcode, _, payload := tc.readFrame()
+	var msg Envelope
+	if opcode != TextMessage || json.Unmarshal(payload, &msg) != nil || msg.Type != msgType {
+		tc.t.Fatalf("got opcode %d payload %q, want a %s envelope", opcode, payload, msgType)
+	}
+	return msg
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHubRoomsAndPresence(t *testing.T) {
+	hub, ts := newHubServer(t, HubConfig{AnnouncePresence: true})
+	alice := dialHub(t, ts, "alice")
+	alice.send(Envelope{Type: TypeJoin, Room: "lobby"})
+	alice.expectEnvelope(TypePresenceJoin)
+	waitFor(t, "alice in lobby", func() bool { return reflect.DeepEqual(hub.Presence("lobby"), []string{"alice"}) })
+
+	bob := dialHub(t, ts, "bob")
+	bob.send(Envelope{Type: TypeJoin, Room: "lobby"})
+	if msg := alice.expectEnvelope(TypePresenceJoin); string(msg.Data) != `{"id":"bob"}` {
+		t.Errorf("alice got presence %s, want bob", msg.Data)
+	}
+	bob.expectEnvelope(TypePresenceJoin)
+	waitFor(t, "bob in lobby", func() bool { return len(hub.Presence("lobby")) == 2 })
+
+	hub.Broadcast("lobby", "chat", "hi")
+	for _, tc := range []*testClient{alice, bob} {
+		if msg := tc.expectEnvelope("chat"); string(msg.Data) != `"hi"` || msg.Room != "lobby" {
+			t.Errorf("got %+v", msg)
+		}
+	}
+
+	bob.conn.Close()
+	if msg := alice.expectEnvelope(TypePresenceLeave); string(msg.Data) != `{"id":"bob"}` {
+		t.Errorf("alice got presence %s, want bob leaving", msg.Data)
+	}
+	if got := hub.Rooms(); !reflect.DeepEqual(got, []string{"lobby"}) {
+		t.Errorf("rooms = %v", got)
+	}
+}
+
+func TestHubSlowClient(t *testing.T) {
+	hub := NewHub(HubConfig{SendQueue: 1})
+	client := &Client{hub: hub, rooms: map[string]struct{}{}, send: make(chan []byte, 1)}
+	if err := client.Send("a", 1); err != nil {
+		t.Fatal(err)
+	}
+	// nobody drains the queue, the second message overflows it
+	if err := client.Send("a", 2); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("got %v for a full queue, want ErrClientClosed", err)
+	}
+	if client.closeCode != ClosePolicyViolation {
+		t.Errorf("close code = %d, want %d", client.closeCode, ClosePolicyViolation)
+	}
+	if err := client.Send("a", 3); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("got %v after close, want ErrClientClosed", err)
+	}
+	// the writer still flushes what was queued before the close
+	if _, ok := <-client.send; !ok {
+		t.Errorf("queued message was dropped")
+	}
+	if _, ok := <-client.send; ok {
+		t.Errorf("queue is still open")
+	}
+}
+
+func TestHubShutdownDrains(t *testing.T) {
+	hub, ts := newHubServer(t, HubConfig{})
+	alice := dialHub(t, ts, "alice")
+	alice.send(Envelope{Type: TypeJoin, Room: "lobby"})
+	waitFor(t, "alice in lobby", func() bool { return len(hub.Presence("lobby")) == 1 })
+
+	hub.Broadcast("lobby", "last", "words")
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		done <- hub.Shutdown(ctx)
+	}()
+	// queued messages come before the going-away close frame
+	alice.expectEnvelope("last")
+	alice.expectClose(CloseGoingAway)
+	reply := binary.BigEndian.AppendUint16(nil, CloseGoingAway)
+	alice.writeFrame(true, false, CloseMessage, reply, false)
+	if err := <-done; err != nil {
+		t.Fatalf("Shutdown = %v", err)
+	}
+
+	tc := dial(t, ts, nil)
+	if tc.resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got %d for a client after Shutdown, want 503", tc.resp.StatusCode)
+	}
+}
+
+func TestHubShutdownDeadline(t *testing.T) {
+	hub, ts := newHubServer(t, HubConfig{})
+	alice := dialHub(t, ts, "alice")
+	waitFor(t, "registration", func() bool {
+		hub.mu.RLock()
+		defer hub.mu.RUnlock()
+		return len(hub.clients) == 1
+	})
+	// alice never answers the close frame
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := hub.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v, want DeadlineExceeded", err)
+	}
+	alice.expectClose(CloseGoingAway)
+}
@@ -0,0 +1,174 @@
+// Package websocket implements the server side of RFC 6455 on top of gee,
+// including permessage-deflate (RFC 7692), without third-party packages.
+//
+//	r.GET("/ws", func(c *gee.Context) {
+//		conn, err := websocket.Upgrade(c)
+//		if err != nil {
+//			return
+//		}
+//		defer conn.Close()
+//		for {
+//			messageType, p, err := conn.ReadMessage()
+//			if err != nil {
+//				return
+//			}
+//			conn.WriteMessage(messageType, p)
+//		}
+//	})
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gee"
+)
+
+// keyGUID is appended to Sec-WebSocket-Key to compute Sec-WebSocket-Accept
+const keyGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// ErrBadHandshake is returned by Upgrade when the request is not a valid
+// WebSocket opening handshake, the client has already been answered
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+// Upgrader turns a gee request into a WebSocket connection
+type Upgrader struct {
+	// ReadLimit is the default maximum message size in bytes, 0 means 32 MiB
+	ReadLimit int64
+	// Subprotocols are the supported protocols in order of preference
+	Subprotocols []string
+	// CheckOrigin rejects cross-site requests, by default the Origin
+	// header must be absent or match the Host header
+	CheckOrigin func(r *http.Request) bool
+	// EnableCompression negotiates permessage-deflate when the client offers it
+	EnableCompression bool
+}
+
+// Upgrade performs the handshake with the default Upgrader
+func Upgrade(c *gee.Context) (*Conn, error) {
+	var u Upgrader
+	return u.Upgrade(c)
+}
+
+// Upgrade validates the opening handshake, answers with 101 Switching
+// Protocols and takes over the underlying connection
+func (u *Upgrader) Upgrade(c *gee.Context) (*Conn, error) {
+	r := c.Req
+	if r.Method != http.MethodGet {
+		return nil, u.fail(c, http.StatusMethodNotAllowed, "request method is not GET")
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") {
+		return nil, u.fail(c, http.StatusBadRequest, "'upgrade' token not found in 'Connection' header")
+	}
+	if !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, u.fail(c, http.StatusBadRequest, "'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.SetHeader("Sec-WebSocket-Version", "13")
+		return nil, u.fail(c, http.StatusUpgradeRequired, "unsupported version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, u.fail(c, http.StatusBadRequest, "'Sec-WebSocket-Key' header is missing or invalid")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = checkSameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, u.fail(c, http.StatusForbidden, "origin not allowed")
+	}
+
+	subprotocol := u.selectSubprotocol(r)
+	compress := u.EnableCompression && negotiateDeflate(r.Header)
+
+	netConn, brw, err := http.NewResponseController(c.Writer).Hijack()
+	if err != nil {
+		return nil, u.fail(c, http.StatusInternalServerError, err.Error())
+	}
+	// drop the deadlines the http.Server may have set for the request
+	netConn.SetDeadline(time.Time{})
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	if compress {
+		b.WriteString("Sec-WebSocket-Extensions: " + deflateResponse + "\r\n")
+	}
+	b.WriteString("\r\n")
+	if _, err := netConn.Write([]byte(b.String())); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	conn := newConn(netConn, brw.Reader, compress)
+	conn.subprotocol = subprotocol
+	conn.SetReadLimit(u.ReadLimit)
+	return conn, nil
+}
+
+func (u *Upgrader) fail(c *gee.Context, code int, reason string) error {
+	c.String(code, "%d %s: %s\n", code, strings.ToUpper(http.StatusText(code)), reason)
+	return ErrBadHandshake
+}
+
+func (u *Upgrader) selectSubprotocol(r *http.Request) string {
+	offered := headerTokens(r.Header, "Sec-WebSocket-Protocol")
+	for _, supported := range u.Subprotocols {
+		for _, protocol := range offered {
+			if protocol == supported {
+				return protocol
+			}
+		}
+	}
+	return ""
+}
+
+// acceptKey computes Sec-WebSocket-Accept from Sec-WebSocket-Key
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + keyGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func checkSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// headerTokens returns the comma separated tokens of every header field key
+func headerTokens(header http.Header, key string) []string {
+	var tokens []string
+	for _, value := range header.Values(key) {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+func headerContains(header http.Header, key string, token string) bool {
+	for _, t := range headerTokens(header, key) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
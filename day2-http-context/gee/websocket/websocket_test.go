@@ -0,0 +1,285 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gee"
+)
+
+// testClient is a minimal in-process WebSocket client driving the server
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+	resp *http.Response
+}
+
+func dial(t *testing.T, ts *httptest.Server, header http.Header) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	req, _ := http.NewRequest("GET", ts.URL+"/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testClient{t: t, conn: conn, br: br, resp: resp}
+}
+
+// writeFrame sends a frame, masked unless unmasked is set
+func (tc *testClient) writeFrame(fin bool, rsv1 bool, opcode int, payload []byte, unmasked bool) {
+	tc.t.Helper()
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	if rsv1 {
+		b0 |= 0x40
+	}
+	frame := []byte{b0}
+	maskBit := byte(0x80)
+	if unmasked {
+		maskBit = 0
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	data := append([]byte(nil), payload...)
+	if !unmasked {
+		key := []byte{0x37, 0xfa, 0x21, 0x3d}
+		frame = append(frame, key...)
+		for i := range data {
+			data[i] ^= key[i&3]
+		}
+	}
+	if _, err := tc.conn.Write(append(frame, data...)); err != nil {
+		tc.t.Fatal(err)
+	}
+}
+
+// readFrame reads one unmasked server frame
+func (tc *testClient) readFrame() (opcode int, rsv1 bool, payload []byte) {
+	tc.t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(tc.br, head[:]); err != nil {
+		tc.t.Fatal(err)
+	}
+	if head[1]&0x80 != 0 {
+		tc.t.Fatal("server frame is masked")
+	}
+	length := int(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(tc.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(tc.br, ext[:])
+		length = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(tc.br, payload); err != nil {
+		tc.t.Fatal(err)
+	}
+	return int(head[0] & 0x0f), head[0]&0x40 != 0, payload
+}
+
+func (tc *testClient) expectClose(code int) {
+	tc.t.Helper()
+	opcode, _, payload := tc.readFrame()
+	if opcode != CloseMessage || len(payload) < 2 {
+		tc.t.Fatalf("got opcode %d payload %q, want close frame", opcode, payload)
+	}
+	if got := int(binary.BigEndian.Uint16(payload)); got != code {
+		tc.t.Fatalf("close code = %d, want %d", got, code)
+	}
+}
+
+// newEchoServer echoes every message and reports the final read error
+func newEchoServer(t *testing.T, u *Upgrader, readLimit int64) (*httptest.Server, chan error) {
+	errs := make(chan error, 1)
+	r := gee.New()
+	r.GET("/ws", func(c *gee.Context) {
+		conn, err := u.Upgrade(c)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if readLimit > 0 {
+			conn.SetReadLimit(readLimit)
+		}
+		for {
+			messageType, p, err := conn.ReadMessage()
+			if err != nil {
+				errs <- err
+				return
+			}
+			conn.WriteMessage(messageType, p)
+		}
+	})
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	return ts, errs
+}
+
+func TestHandshake(t *testing.T) {
+	ts, _ := newEchoServer(t, &Upgrader{Subprotocols: []string{"chat"}}, 0)
+	tc := dial(t, ts, http.Header{"Sec-Websocket-Protocol": {"superchat, chat"}})
+	if tc.resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", tc.resp.StatusCode)
+	}
+	if got := tc.resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", got)
+	}
+	if got := tc.resp.Header.Get("Sec-WebSocket-Protocol"); got != "chat" {
+		t.Errorf("Sec-WebSocket-Protocol = %q, want chat", got)
+	}
+
+	bad := dial(t, ts, http.Header{"Sec-Websocket-Version": {"8"}})
+	if bad.resp.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("status for version 8 = %d, want 426", bad.resp.StatusCode)
+	}
+	foreign := dial(t, ts, http.Header{"Origin": {"http://evil.example"}})
+	if foreign.resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status for a foreign origin = %d, want 403", foreign.resp.StatusCode)
+	}
+}
+
+func TestEchoAndFragmentation(t *testing.T) {
+	ts, _ := newEchoServer(t, &Upgrader{}, 0)
+	tc := dial(t, ts, nil)
+
+	tc.writeFrame(true, false, TextMessage, []byte("hello"), false)
+	if opcode, _, p := tc.readFrame(); opcode != TextMessage || string(p) != "hello" {
+		t.Fatalf("echo = %d %q", opcode, p)
+	}
+
+	// a ping between fragments is answered before the message completes
+	tc.writeFrame(false, false, BinaryMessage, []byte("frag"), false)
+	tc.writeFrame(true, false, PingMessage, []byte("p"), false)
+	tc.writeFrame(false, false, continuationFrame, []byte("men"), false)
+	tc.writeFrame(true, false, continuationFrame, []byte("ted"), false)
+	if opcode, _, p := tc.readFrame(); opcode != PongMessage || string(p) != "p" {
+		t.Fatalf("pong = %d %q", opcode, p)
+	}
+	if opcode, _, p := tc.readFrame(); opcode != BinaryMessage || string(p) != "fragmented" {
+		t.Fatalf("echo = %d %q", opcode, p)
+	}
+
+	big := bytes.Repeat([]byte("x"), 70000)
+	tc.writeFrame(true, false, BinaryMessage, big, false)
+	if _, _, p := tc.readFrame(); !bytes.Equal(p, big) {
+		t.Fatalf("echo of %d bytes returned %d bytes", len(big), len(p))
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	ts, errs := newEchoServer(t, &Upgrader{}, 0)
+	tc := dial(t, ts, nil)
+	tc.writeFrame(true, false, CloseMessage, FormatCloseMessage(CloseGoingAway, "bye"), false)
+	tc.expectClose(CloseGoingAway)
+	err := <-errs
+	if !IsCloseError(err, CloseGoingAway) {
+		t.Fatalf("ReadMessage error = %v, want close 1001", err)
+	}
+}
+
+func TestProtocolViolations(t *testing.T) {
+	tests := []struct {
+		name string
+		send func(tc *testClient)
+		code int
+	}{
+		{"unmasked frame", func(tc *testClient) {
+			tc.writeFrame(true, false, TextMessage, []byte("hi"), true)
+		}, CloseProtocolError},
+		{"invalid utf8", func(tc *testClient) {
+			tc.writeFrame(true, false, TextMessage, []byte{0xff, 0xfe}, false)
+		}, CloseInvalidFramePayload},
+		{"orphan continuation", func(tc *testClient) {
+			tc.writeFrame(true, false, continuationFrame, []byte("x"), false)
+		}, CloseProtocolError},
+		{"fragmented ping", func(tc *testClient) {
+			tc.writeFrame(false, false, PingMessage, []byte("x"), false)
+		}, CloseProtocolError},
+		{"rsv1 without compression", func(tc *testClient) {
+			tc.writeFrame(true, true, TextMessage, []byte("x"), false)
+		}, CloseProtocolError},
+		{"read limit", func(tc *testClient) {
+			tc.writeFrame(true, false, BinaryMessage, make([]byte, 200), false)
+		}, CloseMessageTooBig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, errs := newEchoServer(t, &Upgrader{}, 100)
+			tc := dial(t, ts, nil)
+			tt.send(tc)
+			tc.expectClose(tt.code)
+			if err := <-errs; err == nil {
+				t.Fatal("ReadMessage returned no error")
+			} else if tt.code == CloseMessageTooBig && !errors.Is(err, ErrReadLimit) {
+				t.Fatalf("ReadMessage error = %v, want ErrReadLimit", err)
+			}
+		})
+	}
+}
+
+func TestPermessageDeflate(t *testing.T) {
+	ts, _ := newEchoServer(t, &Upgrader{EnableCompression: true}, 0)
+	tc := dial(t, ts, http.Header{"Sec-Websocket-Extensions": {"permessage-deflate; client_max_window_bits"}})
+	if ext := tc.resp.Header.Get("Sec-WebSocket-Extensions"); !strings.HasPrefix(ext, "permessage-deflate") {
+		t.Fatalf("Sec-WebSocket-Extensions = %q", ext)
+	}
+
+	message := strings.Repeat("gee websocket ", 20)
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.BestCompression)
+	w.Write([]byte(message))
+	w.Flush()
+	tc.writeFrame(true, true, TextMessage, bytes.TrimSuffix(buf.Bytes(), deflateTail), false)
+
+	opcode, rsv1, p := tc.readFrame()
+	if opcode != TextMessage || !rsv1 {
+		t.Fatalf("got opcode %d rsv1 %v, want a compressed text frame", opcode, rsv1)
+	}
+	inflated, err := io.ReadAll(flate.NewReader(io.MultiReader(bytes.NewReader(p), bytes.NewReader(deflateTail))))
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatal(err)
+	}
+	if string(inflated) != message {
+		t.Fatalf("inflated echo = %q", inflated)
+	}
+}
@@ -5,9 +5,14 @@ package main
 $ curl -i http://localhost:9999/
 HTTP/1.1 200 OK
 Date: Mon, 12 Aug 2019 16:52:52 GMT
-Content-Length: 18
 Content-Type: text/html; charset=utf-8
-<h1>Hello Gee</h1>
+<!DOCTYPE html>
+<html>
+<head><title>Hello Gee</title></head>
+<body>
+<h1>HELLO GEE</h1>
+</body>
+</html>
 
 (2)
 $ curl "http://localhost:9999/hello?name=geektutu"
@@ -23,15 +28,21 @@ $ curl "http://localhost:9999/xxx"
 */
 
 import (
+	"html/template"
 	"net/http"
+	"strings"
 
 	"gee"
 )
 
 func main() {
 	r := gee.New()
+	r.Use(gee.Logger(), gee.Recovery())
+	r.SetFuncMap(template.FuncMap{"upper": strings.ToUpper})
+	r.SetHTMLLayouts("templates/layouts/*.tmpl")
+	r.LoadHTMLGlob("templates/*.tmpl")
 	r.GET("/", func(c *gee.Context) {
-		c.HTML(http.StatusOK, "<h1>Hello Gee</h1>")
+		c.HTML(http.StatusOK, "index.tmpl", gee.H{"title": "Hello Gee"})
 	})
 	r.GET("/hello", func(c *gee.Context) {
 		// expect /hello?name=geektutu